@@ -0,0 +1,161 @@
+// Package api exposes the jobs over a RESTful HTTP API.
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// handler serves a single route, params contain the values of the placeholders in the route pattern
+type handler func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+// route maps the methods of a single path pattern, e.g. /jobs/{id}, to their handlers
+type route struct {
+	pattern  []string
+	handlers map[string]handler
+}
+
+// Error is the structured error returned by the API
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Codes of the errors returned by the API
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeInvalidSchedule  = "invalid_schedule"
+	CodeInvalidJob       = "invalid_job"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeNameTaken        = "name_taken"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+func newRoute(pattern string, handlers map[string]handler) route {
+	return route{pattern: splitPath(pattern), handlers: handlers}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// match checks the path against the pattern and collects the values of the placeholders
+func (rt route) match(path []string) (map[string]string, bool) {
+	if len(path) != len(rt.pattern) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for ix, p := range rt.pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if path[ix] == "" {
+				return nil, false
+			}
+			params[strings.Trim(p, "{}")] = path[ix]
+			continue
+		}
+		if p != path[ix] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// serve dispatches the request to the first matching route
+func serve(routes []route, w http.ResponseWriter, r *http.Request) {
+	path := splitPath(r.URL.Path)
+	for _, rt := range routes {
+		params, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+
+		h, ok := rt.handlers[r.Method]
+		if !ok {
+			allowed := make([]string, 0, len(rt.handlers))
+			for m := range rt.handlers {
+				allowed = append(allowed, m)
+			}
+			w.Header().Set("Allow", strings.Join(sortedMethods(allowed), ", "))
+			writeError(w, Error{Status: http.StatusMethodNotAllowed, Code: CodeMethodNotAllowed,
+				Message: "Method " + r.Method + " is not allowed for " + r.URL.Path})
+			return
+		}
+		h(w, r, params)
+		return
+	}
+	writeError(w, Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "No resource found at " + r.URL.Path})
+}
+
+// sortedMethods orders the methods the same way every time, i.e. in the order of the CRUD operations
+func sortedMethods(methods []string) []string {
+	order := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	sorted := make([]string, 0, len(methods))
+	for _, o := range order {
+		for _, m := range methods {
+			if m == o {
+				sorted = append(sorted, m)
+			}
+		}
+	}
+	return sorted
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with the structured error, any other error is reported as internal
+func writeError(w http.ResponseWriter, err error) {
+	apiErr, ok := err.(Error)
+	if !ok {
+		apiErr = Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+	}
+	writeJSON(w, apiErr.Status, map[string]Error{"error": apiErr})
+}
+
+// decode reads the JSON body of the request, unknown fields are rejected
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Unable to decode the request: " + err.Error()}
+	}
+	return nil
+}
+
+// actorHeader identifies who made the request, unless the body says so
+const actorHeader = "X-Actor"
+
+// requestActor is the actor given by the body or by the header
+func requestActor(r *http.Request, actor string) string {
+	if actor != "" {
+		return actor
+	}
+	return r.Header.Get(actorHeader)
+}
+
+// decodeOptional is decode accepting also the empty body, v is left intact then
+func decodeOptional(r *http.Request, v interface{}) error {
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		return nil
+	}
+	r.Body = ioutil.NopCloser(body)
+	return decode(r, v)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/store"
+)
+
+// maxBackfillRuns limits the number of the runs of a single backfill
+const maxBackfillRuns = 1000
+
+// backfillRequest is the body of the request backfilling the Job over the window from-to (both inclusive)
+type backfillRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Parallelism is the number of the runs running at the same time, defaults to 1
+	Parallelism int `json:"parallelism"`
+}
+
+func errInvalidBackfill(field, message string) error {
+	return Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: message, Field: field}
+}
+
+// validate checks the window and the parallelism
+func (req backfillRequest) validate() error {
+	switch {
+	case req.From.IsZero():
+		return errInvalidBackfill("from", "The start of the window must be set")
+	case req.To.IsZero():
+		return errInvalidBackfill("to", "The end of the window must be set")
+	case req.To.Before(req.From):
+		return errInvalidBackfill("to", "The end of the window must not precede its start")
+	case req.Parallelism < 0:
+		return errInvalidBackfill("parallelism", "The parallelism must not be negative")
+	}
+	return nil
+}
+
+// backfill runs the Job for every fire time of its schedule within the window, the runs pass the fire time
+// to the command as their logical time. With ?preview=true the runs are only listed, nothing is started.
+func (s *Server) backfill(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req backfillRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	j, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	times, err := j.Schedule.Times(req.From, req.To, maxBackfillRuns+1)
+	if err != nil {
+		writeError(w, Error{Status: http.StatusBadRequest, Code: CodeInvalidSchedule, Message: err.Error(), Field: "schedule"})
+		return
+	}
+	if len(times) > maxBackfillRuns {
+		writeError(w, errInvalidBackfill("to", "The window holds more than "+strconv.Itoa(maxBackfillRuns)+" fire times, split it"))
+		return
+	}
+
+	now := s.Now()
+	runs := make([]job.Run, 0, len(times))
+	for _, t := range times {
+		run := job.Run{ID: store.NewID(), JobID: j.ID, ScheduledTime: t, Trigger: job.TriggerBackfill, Attempt: 1}
+		run.Transition(job.StatusPlanned, now)
+		runs = append(runs, run)
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		writeJSON(w, http.StatusOK, runs)
+		return
+	}
+	if s.Runner == nil {
+		writeError(w, Error{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: "The runs are not executed by this server"})
+		return
+	}
+
+	for ix := range runs {
+		runs[ix].Transition(job.StatusQueued, now)
+		if err := s.runs.SaveRun(runs[ix]); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	s.Runner.Backfill(j, runs, req.Parallelism)
+	writeJSON(w, http.StatusAccepted, runs)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/store"
+)
+
+func TestBackfill(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2020, 1, d, h, 0, 0, 0, time.Local)
+	}
+	window := func(from, to time.Time, extra string) string {
+		return fmt.Sprintf(`{"from": %q, "to": %q%s}`, from.Format(time.RFC3339), to.Format(time.RFC3339), extra)
+	}
+
+	st := store.NewMemory()
+	srv := NewServer(st)
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+
+	tests := map[string]struct {
+		path   string
+		body   string
+		status int
+		want   []time.Time
+	}{
+		"unavailable":   {path: "/jobs/backup/backfill", body: window(day(1, 0), day(3, 23), ""), status: http.StatusServiceUnavailable},
+		"preview":       {path: "/jobs/backup/backfill?preview=true", body: window(day(1, 0), day(3, 23), ""), status: http.StatusOK, want: []time.Time{day(1, 2), day(2, 2), day(3, 2)}},
+		"empty window":  {path: "/jobs/backup/backfill?preview=true", body: window(day(1, 3), day(1, 23), ""), status: http.StatusOK, want: []time.Time{}},
+		"missing from":  {path: "/jobs/backup/backfill", body: `{"to": "2020-01-01T00:00:00Z"}`, status: http.StatusBadRequest},
+		"reversed":      {path: "/jobs/backup/backfill", body: window(day(3, 0), day(1, 0), ""), status: http.StatusBadRequest},
+		"too many":      {path: "/jobs/backup/backfill", body: window(time.Date(2010, 1, 1, 0, 0, 0, 0, time.Local), day(1, 0), ""), status: http.StatusBadRequest},
+		"negative":      {path: "/jobs/backup/backfill", body: window(day(1, 0), day(3, 23), `, "parallelism": -1`), status: http.StatusBadRequest},
+		"missing job":   {path: "/jobs/missing/backfill", body: window(day(1, 0), day(3, 23), ""), status: http.StatusNotFound},
+		"unknown field": {path: "/jobs/backup/backfill", body: `{"since": "2020-01-01T00:00:00Z"}`, status: http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(srv, http.MethodPost, test.path, test.body)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.want == nil {
+				return
+			}
+			var runs []job.Run
+			json.Unmarshal(rec.Body.Bytes(), &runs)
+			got := make([]time.Time, 0, len(runs))
+			for _, run := range runs {
+				if run.JobID != created.ID || run.Trigger != job.TriggerBackfill || run.Status != job.StatusPlanned {
+					t.Fatalf("Unexpected run: %#v", run)
+				}
+				got = append(got, run.ScheduledTime.Local())
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %v, got: %v", test.want, got)
+			}
+		})
+	}
+
+	// the preview stores nothing
+	if page, _ := st.ListRuns(store.RunFilter{}); len(page.Runs) != 0 {
+		t.Fatalf("Expected: no runs, got: %#v", page.Runs)
+	}
+
+	dispatcher := &recordDispatcher{}
+	srv.Runner = dispatcher
+	rec := do(srv, http.MethodPost, "/jobs/backup/backfill", window(day(1, 0), day(3, 23), `, "parallelism": 2`))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected: %v, got: %v %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if len(dispatcher.runs) != 3 || dispatcher.parallelism != 2 || dispatcher.jobs[0].ID != created.ID {
+		t.Fatalf("Unexpected backfill: %#v", dispatcher)
+	}
+	for _, run := range dispatcher.runs {
+		saved, err := st.GetRun(run.ID)
+		if err != nil || saved.Status != job.StatusQueued || !reflect.DeepEqual(run, saved) {
+			t.Fatalf("Expected: %#v, got: %#v %v", run, saved, err)
+		}
+	}
+}
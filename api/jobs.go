@@ -0,0 +1,444 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/schedule"
+	"github.com/kubistmi/plango/store"
+)
+
+// previewWindow is the default length of the window compared by the preview of the schedule change
+const previewWindow = 7 * 24 * time.Hour
+
+// jobRequest is the body of the requests creating or modifying a Job, missing fields are nil
+type jobRequest struct {
+	Name      *string         `json:"name"`
+	Namespace *string         `json:"namespace"`
+	Schedule  json.RawMessage `json:"schedule"`
+	Active    *bool           `json:"active"`
+	Command   *string         `json:"command"`
+	Args      *[]string       `json:"args"`
+	// Config replaces the whole Config of the Job
+	Config  *job.Config  `json:"config"`
+	Misfire *job.Misfire `json:"misfire"`
+	// DependsOn is raw to tell apart the missing dependencies and the removed ones (null)
+	DependsOn json.RawMessage `json:"dependsOn"`
+	// Version is the version of the Job the modification is based on, see expectedVersion
+	Version *int `json:"version"`
+}
+
+// preview is the response to the modification of the Job with ?preview=true, the Job is not saved
+type preview struct {
+	Job  job.Job       `json:"job"`
+	Diff schedule.Diff `json:"diff"`
+}
+
+// apply sets the fields present in the request to the Job
+func (req jobRequest) apply(j *job.Job) error {
+	if req.Name != nil {
+		j.Name = *req.Name
+	}
+	if req.Namespace != nil {
+		j.Namespace = *req.Namespace
+	}
+	if req.Schedule != nil {
+		var sched schedule.Schedule
+		if err := json.Unmarshal(req.Schedule, &sched); err != nil {
+			return Error{Status: http.StatusBadRequest, Code: CodeInvalidSchedule, Message: err.Error(), Field: "schedule"}
+		}
+		j.Schedule = sched
+	}
+	if req.Active != nil {
+		j.Active = *req.Active
+	}
+	if req.Command != nil {
+		j.Command = *req.Command
+	}
+	if req.Args != nil {
+		j.Args = *req.Args
+	}
+	if req.Config != nil {
+		j.Config = *req.Config
+	}
+	j.Config = j.Config.WithDefaults()
+	if req.Misfire != nil {
+		j.Misfire = *req.Misfire
+	}
+	if req.DependsOn != nil {
+		var deps *job.Dependencies
+		if err := json.Unmarshal(req.DependsOn, &deps); err != nil {
+			return Error{Status: http.StatusBadRequest, Code: CodeInvalidJob, Message: err.Error(), Field: "dependsOn"}
+		}
+		j.DependsOn = deps
+	}
+
+	return invalidJob(j.Validate())
+}
+
+// invalidJob translates the ValidationError of the Job to the API error, the other errors are returned as they are
+func invalidJob(err error) error {
+	if verr, ok := err.(job.ValidationError); ok {
+		return Error{Status: http.StatusBadRequest, Code: CodeInvalidJob, Message: verr.Error(), Field: verr.Field}
+	}
+	return err
+}
+
+func errJobNotFound(id string) error {
+	return Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "Job " + id + " not found"}
+}
+
+// storeError translates the errors of the JobStore to the API errors
+func storeError(err error, id string) error {
+	switch err {
+	case store.ErrNotFound:
+		return errJobNotFound(id)
+	case store.ErrConflict:
+		return Error{Status: http.StatusConflict, Code: CodeConflict, Message: "Job " + id + " was changed in the meantime, fetch its current version"}
+	case store.ErrNameTaken:
+		return Error{Status: http.StatusConflict, Code: CodeNameTaken, Message: "Another job in the namespace is already named " + id, Field: "name"}
+	}
+	return err
+}
+
+// expectedVersion finds the version of the Job the request is based on: the header If-Match,
+// the field version of the body or, if neither is present, the current version
+func expectedVersion(r *http.Request, body *int, current int) (int, error) {
+	if match := r.Header.Get("If-Match"); match != "" {
+		v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(match, "W/"), `"`))
+		if err != nil {
+			return 0, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Unable to parse If-Match, expected the version of the job: " + match}
+		}
+		return v, nil
+	}
+	if body != nil {
+		return *body, nil
+	}
+	return current, nil
+}
+
+// writeJob responds with the Job and its version as the ETag
+func writeJob(w http.ResponseWriter, status int, j job.Job) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(j.Version)+`"`)
+	writeJSON(w, status, j)
+}
+
+// checkDependencies checks the upstream jobs of the Job against the stored ones
+func (s *Server) checkDependencies(j job.Job) error {
+	if j.DependsOn == nil {
+		return nil
+	}
+	jobs, err := s.jobs.List()
+	if err != nil {
+		return err
+	}
+	return invalidJob(job.CheckDependencies(j, jobs))
+}
+
+// dependents lists the names of the jobs depending on the Job
+func (s *Server) dependents(id string) ([]string, error) {
+	jobs, err := s.jobs.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, j := range jobs {
+		if j.DependsOn == nil {
+			continue
+		}
+		for _, upstream := range j.DependsOn.Jobs {
+			if upstream == id {
+				names = append(names, j.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// lookupJob finds the Job by the ID or the name (within the namespace given by the query parameter) from the path
+func (s *Server) lookupJob(r *http.Request, ref string) (job.Job, error) {
+	j, err := store.Lookup(s.jobs, r.URL.Query().Get("namespace"), ref)
+	if err != nil {
+		return job.Job{}, storeError(err, ref)
+	}
+	return j, nil
+}
+
+// listJobs lists all the jobs or only the ones in the namespace given by the query parameter
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	jobs, err := s.jobs.List()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		filtered := make([]job.Job, 0, len(jobs))
+		for _, j := range jobs {
+			if j.Namespace == namespace {
+				filtered = append(filtered, j)
+			}
+		}
+		jobs = filtered
+	}
+	// every ?label=key=value has to match
+	for _, label := range r.URL.Query()["label"] {
+		key, value := label, ""
+		if i := strings.Index(label, "="); i >= 0 {
+			key, value = label[:i], label[i+1:]
+		}
+		filtered := make([]job.Job, 0, len(jobs))
+		for _, j := range jobs {
+			if j.Config.HasLabel(key, value) {
+				filtered = append(filtered, j)
+			}
+		}
+		jobs = filtered
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req jobRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the jobs are active unless requested otherwise
+	j := job.Job{Active: true}
+	if err := req.apply(&j); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.checkDependencies(j); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	j, err := s.jobs.Create(j)
+	if err != nil {
+		writeError(w, storeError(err, j.Name))
+		return
+	}
+
+	s.Scheduler.Upsert(j)
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	writeJob(w, http.StatusCreated, j)
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	j, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJob(w, http.StatusOK, j)
+}
+
+// replaceJob replaces the whole definition of the Job, its pause is kept
+func (s *Server) replaceJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s.modifyJob(w, r, params["id"], func(old job.Job) job.Job {
+		return job.Job{ID: old.ID, Namespace: old.Namespace, Active: true, Pause: old.Pause}
+	})
+}
+
+// updateJob changes only the fields present in the request
+func (s *Server) updateJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s.modifyJob(w, r, params["id"], func(old job.Job) job.Job {
+		return old
+	})
+}
+
+// modifyJob applies the request to the Job prepared by `base` from the stored one
+func (s *Server) modifyJob(w http.ResponseWriter, r *http.Request, ref string, base func(job.Job) job.Job) {
+	var req jobRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	old, err := s.lookupJob(r, ref)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	j := base(old)
+	if err := req.apply(&j); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.checkDependencies(j); err != nil {
+		writeError(w, err)
+		return
+	}
+	if j.Version, err = expectedVersion(r, req.Version, old.Version); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		p, err := s.preview(r, old, j)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+		return
+	}
+
+	updated, err := s.jobs.Update(j)
+	if err != nil {
+		if err == store.ErrNameTaken {
+			writeError(w, storeError(err, j.Name))
+			return
+		}
+		writeError(w, storeError(err, ref))
+		return
+	}
+	s.Scheduler.Upsert(updated)
+	writeJob(w, http.StatusOK, updated)
+}
+
+// preview compares the fire times of the old and the new schedule of the Job,
+// the window is given by the parameters `from` and `to` (RFC3339) and defaults to the following week
+func (s *Server) preview(r *http.Request, old, updated job.Job) (preview, error) {
+	from, to := s.Now(), time.Time{}
+	var err error
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return preview{}, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Unable to parse from: " + err.Error()}
+		}
+	}
+	to = from.Add(previewWindow)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return preview{}, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Unable to parse to: " + err.Error()}
+		}
+	}
+
+	diff, err := schedule.Compare(old.Schedule, updated.Schedule, from, to)
+	if err != nil {
+		return preview{}, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: err.Error()}
+	}
+	return preview{Job: updated, Diff: diff}, nil
+}
+
+func (s *Server) deleteJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	old, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	version, err := expectedVersion(r, nil, old.Version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	names, err := s.dependents(old.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(names) > 0 {
+		writeError(w, Error{Status: http.StatusConflict, Code: CodeConflict, Message: "Job " + params["id"] + " is a dependency of " + strings.Join(names, ", ")})
+		return
+	}
+	if err := s.jobs.Delete(old.ID, version); err != nil {
+		writeError(w, storeError(err, params["id"]))
+		return
+	}
+	s.Scheduler.Remove(old.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pauseRequest is the body of the request pausing the Job
+type pauseRequest struct {
+	Reason string `json:"reason"`
+	// Actor defaults to the header X-Actor
+	Actor string     `json:"actor"`
+	Until *time.Time `json:"until"`
+	// Version is the version of the Job the pause is based on, see expectedVersion
+	Version *int `json:"version"`
+}
+
+// pauseJob pauses the Job with the reason, until the time given or until it's resumed
+func (s *Server) pauseJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req pauseRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	now := s.Now()
+	if strings.TrimSpace(req.Reason) == "" {
+		writeError(w, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "The reason of the pause must not be empty", Field: "reason"})
+		return
+	}
+	if req.Until != nil && !req.Until.After(now) {
+		writeError(w, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "The pause must last until a future time", Field: "until"})
+		return
+	}
+
+	pause := &job.Pause{Reason: req.Reason, Actor: requestActor(r, req.Actor), At: now, Until: req.Until}
+	s.changeJob(w, r, params["id"], req.Version, func(j *job.Job) {
+		j.Pause = pause
+	})
+}
+
+// resumeJob removes the pause of the Job, resuming the Job that isn't paused does nothing
+func (s *Server) resumeJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req struct {
+		Version *int `json:"version"`
+	}
+	if err := decodeOptional(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.changeJob(w, r, params["id"], req.Version, func(j *job.Job) {
+		j.Pause = nil
+	})
+}
+
+// changeJob stores the change of the Job made by the API itself, rather than by the request body
+func (s *Server) changeJob(w http.ResponseWriter, r *http.Request, ref string, version *int, change func(j *job.Job)) {
+	j, err := s.lookupJob(r, ref)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if j.Version, err = expectedVersion(r, version, j.Version); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	change(&j)
+	updated, err := s.jobs.Update(j)
+	if err != nil {
+		writeError(w, storeError(err, ref))
+		return
+	}
+	s.Scheduler.Upsert(updated)
+	writeJob(w, http.StatusOK, updated)
+}
+
+// plannedRuns lists the following runs of the Job, an inactive Job has none
+func (s *Server) plannedRuns(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	j, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	runs, _ := s.Scheduler.Planned(j.ID)
+	if runs == nil {
+		runs = []job.Run{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
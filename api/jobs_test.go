@@ -0,0 +1,562 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/scheduler"
+	"github.com/kubistmi/plango/store"
+)
+
+func do(srv http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	return serveRequest(srv, httptest.NewRequest(method, path, strings.NewReader(body)))
+}
+
+func serveRequest(srv http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeJob(t *testing.T, rec *httptest.ResponseRecorder) job.Job {
+	t.Helper()
+	var j job.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &j); err != nil {
+		t.Fatalf("Unable to decode %s: %v", rec.Body.String(), err)
+	}
+	return j
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) Error {
+	t.Helper()
+	var body map[string]Error
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unable to decode %s: %v", rec.Body.String(), err)
+	}
+	return body["error"]
+}
+
+const backup = `{"name": "backup", "schedule": "0 0 2 * * *", "command": "backup.sh", "args": ["--full"]}`
+
+func TestCreateJob(t *testing.T) {
+	tests := map[string]struct {
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		"valid":            {body: backup, status: http.StatusCreated},
+		"bounded schedule": {body: `{"name": "ski", "schedule": {"expression": "0 0 6 * * *", "notBefore": "2026-12-01T00:00:00Z"}, "command": "ski.sh"}`, status: http.StatusCreated},
+		"invalid json":     {body: `{"name": `, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		"unknown field":    {body: `{"name": "backup", "color": "red"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		"invalid schedule": {body: `{"name": "backup", "schedule": "0 0 25 * * *", "command": "backup.sh"}`, status: http.StatusBadRequest, code: CodeInvalidSchedule, field: "schedule"},
+		"missing schedule": {body: `{"name": "backup", "command": "backup.sh"}`, status: http.StatusBadRequest, code: CodeInvalidJob, field: "schedule"},
+		"missing command":  {body: `{"name": "backup", "schedule": "0 0 2 * * *"}`, status: http.StatusBadRequest, code: CodeInvalidJob, field: "command"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(NewServer(store.NewMemory()), http.MethodPost, "/jobs", test.body)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.status != http.StatusCreated {
+				got := decodeError(t, rec)
+				if got.Code != test.code || got.Field != test.field {
+					t.Fatalf("Expected: %v %v, got: %#v", test.code, test.field, got)
+				}
+				return
+			}
+
+			got := decodeJob(t, rec)
+			if got.ID == "" || !got.Active || rec.Header().Get("Location") != "/jobs/"+got.ID {
+				t.Fatalf("Unexpected job: %#v, location: %v", got, rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestInvalidJob(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want error
+	}{
+		"valid":   {err: nil, want: nil},
+		"invalid": {err: job.ValidationError{Field: "name", Message: "the name must not be empty"}, want: Error{Status: http.StatusBadRequest, Code: CodeInvalidJob, Message: "Invalid field name: the name must not be empty", Field: "name"}},
+		"other":   {err: store.ErrNotFound, want: store.ErrNotFound},
+	}
+
+	for name, test := range tests {
+		if got := invalidJob(test.err); !reflect.DeepEqual(test.want, got) {
+			t.Fatalf("%s: Expected: %#v, got: %#v", name, test.want, got)
+		}
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	path := "/jobs/" + created.ID
+
+	if got := decodeJob(t, do(srv, http.MethodGet, path, "")); !reflect.DeepEqual(created, got) {
+		t.Fatalf("Expected: %#v, got: %#v", created, got)
+	}
+
+	// PATCH keeps the missing fields
+	rec := do(srv, http.MethodPatch, path, `{"schedule": "0 0 3 * * *", "active": false}`)
+	patched := decodeJob(t, rec)
+	if rec.Code != http.StatusOK || patched.Schedule.String() != "0 0 3 * * *" || patched.Active || patched.Command != "backup.sh" {
+		t.Fatalf("Unexpected patched job: %v %#v", rec.Code, patched)
+	}
+
+	// PUT replaces the whole job
+	rec = do(srv, http.MethodPut, path, `{"name": "backup", "schedule": "0 0 4 * * *", "command": "backup2.sh"}`)
+	replaced := decodeJob(t, rec)
+	if rec.Code != http.StatusOK || replaced.ID != created.ID || replaced.Args != nil || !replaced.Active {
+		t.Fatalf("Unexpected replaced job: %v %#v", rec.Code, replaced)
+	}
+
+	// invalid update doesn't change anything
+	if rec = do(srv, http.MethodPatch, path, `{"schedule": "* *"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected: %v, got: %v", http.StatusBadRequest, rec.Code)
+	}
+
+	var list []job.Job
+	json.Unmarshal(do(srv, http.MethodGet, "/jobs", "").Body.Bytes(), &list)
+	if !reflect.DeepEqual([]job.Job{replaced}, list) {
+		t.Fatalf("Expected: %#v, got: %#v", []job.Job{replaced}, list)
+	}
+
+	if rec = do(srv, http.MethodDelete, path, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNoContent, rec.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+		if rec = do(srv, method, path, `{}`); rec.Code != http.StatusNotFound || decodeError(t, rec).Code != CodeNotFound {
+			t.Fatalf("%s: expected: %v, got: %v", method, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func TestJobVersions(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+
+	rec := do(srv, http.MethodPost, "/jobs", backup)
+	created := decodeJob(t, rec)
+	path := "/jobs/" + created.ID
+	if rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("Expected ETag \"1\", got: %v", rec.Header().Get("ETag"))
+	}
+
+	tests := []struct {
+		method  string
+		ifMatch string
+		body    string
+		status  int
+	}{
+		{method: http.MethodPatch, body: `{"version": 1, "command": "a.sh"}`, status: http.StatusOK},
+		{method: http.MethodPatch, body: `{"version": 1, "command": "b.sh"}`, status: http.StatusConflict},
+		{method: http.MethodPatch, ifMatch: `"1"`, body: `{"command": "b.sh"}`, status: http.StatusConflict},
+		{method: http.MethodPatch, ifMatch: `"2"`, body: `{"command": "b.sh"}`, status: http.StatusOK},
+		{method: http.MethodPatch, ifMatch: `abc`, body: `{"command": "b.sh"}`, status: http.StatusBadRequest},
+		{method: http.MethodPatch, body: `{"command": "c.sh"}`, status: http.StatusOK},
+		{method: http.MethodDelete, ifMatch: `"2"`, status: http.StatusConflict},
+		{method: http.MethodDelete, ifMatch: `W/"4"`, status: http.StatusNoContent},
+	}
+
+	for ix, test := range tests {
+		req := httptest.NewRequest(test.method, path, strings.NewReader(test.body))
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Fatalf("%v: expected: %v, got: %v %s", ix, test.status, rec.Code, rec.Body.String())
+		}
+		if rec.Code == http.StatusConflict && decodeError(t, rec).Code != CodeConflict {
+			t.Fatalf("%v: expected: %v, got: %s", ix, CodeConflict, rec.Body.String())
+		}
+	}
+}
+
+func TestJobNames(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+
+	first := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	team := decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "backup", "namespace": "team", "schedule": "0 0 2 * * *", "command": "backup.sh"}`))
+	if first.ID == team.ID || len(first.ID) != 26 {
+		t.Fatalf("Unexpected IDs: %v %v", first.ID, team.ID)
+	}
+
+	rec := do(srv, http.MethodPost, "/jobs", backup)
+	if rec.Code != http.StatusConflict || decodeError(t, rec).Code != CodeNameTaken {
+		t.Fatalf("Expected: %v, got: %v %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+
+	tests := map[string]struct {
+		path string
+		want string
+	}{
+		"by id":             {path: "/jobs/" + team.ID, want: team.ID},
+		"by name":           {path: "/jobs/backup", want: first.ID},
+		"by name namespace": {path: "/jobs/backup?namespace=team", want: team.ID},
+	}
+	for name, test := range tests {
+		if got := decodeJob(t, do(srv, http.MethodGet, test.path, "")); got.ID != test.want {
+			t.Fatalf("%s: expected: %v, got: %v", name, test.want, got.ID)
+		}
+	}
+
+	var list []job.Job
+	json.Unmarshal(do(srv, http.MethodGet, "/jobs?namespace=team", "").Body.Bytes(), &list)
+	if len(list) != 1 || list[0].ID != team.ID {
+		t.Fatalf("Expected only %v, got: %#v", team.ID, list)
+	}
+
+	if rec := do(srv, http.MethodDelete, "/jobs/backup?namespace=team", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+	srv.Now = func() time.Time { return time.Date(2019, time.Month(10), 7, 0, 0, 0, 0, time.UTC) }
+
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	path := "/jobs/" + created.ID
+
+	rec := do(srv, http.MethodPatch, path+"?preview=true&to=2019-10-09T00:00:00Z", `{"schedule": "0 0 2,14 * * *"}`)
+	var got preview
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response: %v %s", rec.Code, rec.Body.String())
+	}
+	if len(got.Diff.Added) != 2 || len(got.Diff.Removed) != 0 || got.Diff.Before.Count != 2 || got.Diff.After.Count != 4 {
+		t.Fatalf("Unexpected diff: %#v", got.Diff)
+	}
+
+	// the preview doesn't change the job
+	if stored := decodeJob(t, do(srv, http.MethodGet, path, "")); stored.Schedule.String() != "0 0 2 * * *" {
+		t.Fatalf("Expected unchanged schedule, got: %v", stored.Schedule)
+	}
+}
+
+func TestRouting(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+
+	tests := map[string]struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		"unknown path":      {method: http.MethodGet, path: "/unknown", status: http.StatusNotFound},
+		"too deep":          {method: http.MethodGet, path: "/jobs/1/2/3", status: http.StatusNotFound},
+		"collection method": {method: http.MethodDelete, path: "/jobs", status: http.StatusMethodNotAllowed, allow: "GET, POST"},
+		"resource method":   {method: http.MethodPost, path: "/jobs/1", status: http.StatusMethodNotAllowed, allow: "GET, PUT, PATCH, DELETE"},
+		"trailing slash":    {method: http.MethodGet, path: "/jobs/", status: http.StatusOK},
+		"missing job":       {method: http.MethodGet, path: "/jobs/42", status: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(srv, test.method, test.path, "")
+			if rec.Code != test.status || rec.Header().Get("Allow") != test.allow {
+				t.Fatalf("Expected: %v %q, got: %v %q", test.status, test.allow, rec.Code, rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+// recordScheduler lists the changes of the jobs as "upsert <name>" and "remove <id>"
+type recordScheduler struct {
+	noScheduler
+	changes []string
+}
+
+func (s *recordScheduler) Upsert(j job.Job) {
+	s.changes = append(s.changes, "upsert "+j.Name)
+}
+
+func (s *recordScheduler) Remove(id string) {
+	s.changes = append(s.changes, "remove "+id)
+}
+
+func TestSchedulerChanges(t *testing.T) {
+	sched := &recordScheduler{}
+	srv := NewServer(store.NewMemory())
+	srv.Scheduler = sched
+
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	do(srv, http.MethodPatch, "/jobs/"+created.ID, `{"active": false}`)
+	do(srv, http.MethodPatch, "/jobs/"+created.ID+"?preview=true", `{"schedule": "0 0 3 * * *"}`)
+	do(srv, http.MethodPost, "/jobs", `{"name": "backup"}`)
+	do(srv, http.MethodDelete, "/jobs/"+created.ID, "")
+
+	want := []string{"upsert backup", "upsert backup", "remove " + created.ID}
+	if !reflect.DeepEqual(want, sched.changes) {
+		t.Fatalf("Expected: %#v, got: %#v", want, sched.changes)
+	}
+}
+
+func TestPlanned(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+	srv.Scheduler = scheduler.New(scheduler.RealClock{}, scheduler.DispatcherFunc(func(job.Job, job.Run) {}), 3)
+
+	planned := func(ref string) []job.Run {
+		t.Helper()
+		rec := do(srv, http.MethodGet, "/jobs/"+ref+"/planned", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected: %v, got: %v %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var runs []job.Run
+		if err := json.Unmarshal(rec.Body.Bytes(), &runs); err != nil {
+			t.Fatalf("Unable to decode %s: %v", rec.Body.String(), err)
+		}
+		return runs
+	}
+
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	runs := planned("backup")
+	if len(runs) != 3 {
+		t.Fatalf("Expected: 3 runs, got: %#v", runs)
+	}
+	for ix, run := range runs {
+		if run.ID == "" || run.JobID != created.ID || run.Status != job.StatusPlanned || run.Trigger != job.TriggerSchedule ||
+			run.ScheduledTime.Hour() != 2 || (ix > 0 && !run.ScheduledTime.After(runs[ix-1].ScheduledTime)) {
+			t.Fatalf("Unexpected run %v: %#v", ix, run)
+		}
+	}
+
+	// the edit of the schedule replans the runs
+	do(srv, http.MethodPatch, "/jobs/"+created.ID, `{"schedule": "0 0 3 * * *"}`)
+	runs = planned(created.ID)
+	if len(runs) != 3 || runs[0].ScheduledTime.Hour() != 3 {
+		t.Fatalf("Unexpected runs: %#v", runs)
+	}
+
+	do(srv, http.MethodPatch, "/jobs/"+created.ID, `{"active": false}`)
+	if runs = planned(created.ID); len(runs) != 0 {
+		t.Fatalf("Expected: no runs, got: %#v", runs)
+	}
+
+	if rec := do(srv, http.MethodGet, "/jobs/missing/planned", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "flaky", "schedule": "0 0 2 * * *", "command": "fetch.sh",
+		"config": {"env": {"SOURCE": "s3"}, "workDir": "/srv/fetch", "retry": {"maxAttempts": 3, "initialDelay": "30s", "exitCodes": [75]}, "labels": {"team": "data"}}}`))
+	want := job.Config{
+		Env:         map[string]string{"SOURCE": "s3"},
+		WorkDir:     "/srv/fetch",
+		Retry:       &job.RetryPolicy{MaxAttempts: 3, InitialDelay: job.Duration(30 * time.Second), Multiplier: 2, ExitCodes: []int{75}},
+		Concurrency: job.Concurrency{Policy: job.ConcurrencyAllow},
+		Labels:      map[string]string{"team": "data"},
+	}
+	if !reflect.DeepEqual(want, created.Config) {
+		t.Fatalf("Expected: %#v, got: %#v", want, created.Config)
+	}
+
+	// the missing config is kept, the present one replaces it
+	updated := decodeJob(t, do(srv, http.MethodPatch, "/jobs/flaky", `{"command": "fetch2.sh"}`))
+	if !reflect.DeepEqual(want, updated.Config) {
+		t.Fatalf("Expected: %#v, got: %#v", want, updated.Config)
+	}
+	updated = decodeJob(t, do(srv, http.MethodPatch, "/jobs/flaky", `{"config": {}}`))
+	if want := (job.Config{Concurrency: job.Concurrency{Policy: job.ConcurrencyAllow}}); !reflect.DeepEqual(want, updated.Config) {
+		t.Fatalf("Expected: %#v, got: %#v", want, updated.Config)
+	}
+
+	// the free-form config is kept as the extra one
+	legacy := decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "legacy", "schedule": "0 0 2 * * *", "command": "legacy.sh", "config": {"DAY": "monday"}}`))
+	if want := map[string]string{"DAY": "monday"}; !reflect.DeepEqual(want, legacy.Config.Extra) {
+		t.Fatalf("Expected: %#v, got: %#v", want, legacy.Config.Extra)
+	}
+
+	// the mistyped field isn't mistaken for the free-form config
+	rec := do(srv, http.MethodPatch, "/jobs/"+created.ID, `{"config": {"timeout": "5m", "workDir": "relative", "usr": "bob"}}`)
+	if got := decodeError(t, rec); rec.Code != http.StatusBadRequest || got.Code != CodeInvalidRequest {
+		t.Fatalf("Expected: %v %v, got: %v %#v", http.StatusBadRequest, CodeInvalidRequest, rec.Code, got)
+	}
+
+	tests := map[string]struct {
+		body  string
+		field string
+	}{
+		"invalid retry":    {body: `{"config": {"retry": {"maxAttempts": 3, "multiplier": 0.5}}}`, field: "config.retry"},
+		"relative dir":     {body: `{"config": {"workDir": "fetch"}}`, field: "config.workDir"},
+		"invalid env":      {body: `{"config": {"env": {"A=B": "C"}}}`, field: "config.env"},
+		"negative timeout": {body: `{"config": {"timeout": "-1s"}}`, field: "config.timeout"},
+		"invalid policy":   {body: `{"config": {"concurrency": {"policy": "never"}}}`, field: "config.concurrency"},
+	}
+	for name, test := range tests {
+		rec := do(srv, http.MethodPatch, "/jobs/"+created.ID, test.body)
+		if got := decodeError(t, rec); rec.Code != http.StatusBadRequest || got.Field != test.field {
+			t.Fatalf("%s: Expected: %v %v, got: %v %#v", name, http.StatusBadRequest, test.field, rec.Code, got)
+		}
+	}
+}
+
+func TestListJobsByLabel(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+	do(srv, http.MethodPost, "/jobs", `{"name": "etl", "schedule": "0 0 2 * * *", "command": "etl.sh", "config": {"labels": {"team": "data", "tier": "1"}}}`)
+	do(srv, http.MethodPost, "/jobs", `{"name": "report", "schedule": "0 0 6 * * *", "command": "report.sh", "config": {"labels": {"team": "data"}}}`)
+	do(srv, http.MethodPost, "/jobs", `{"name": "backup", "schedule": "0 0 3 * * *", "command": "backup.sh"}`)
+
+	tests := map[string]struct {
+		query string
+		want  []string
+	}{
+		"no label":   {query: "", want: []string{"backup", "etl", "report"}},
+		"one label":  {query: "?label=team=data", want: []string{"etl", "report"}},
+		"two labels": {query: "?label=team=data&label=tier=1", want: []string{"etl"}},
+		"no match":   {query: "?label=team=web", want: []string{}},
+	}
+	for name, test := range tests {
+		rec := do(srv, http.MethodGet, "/jobs"+test.query, "")
+		var jobs []job.Job
+		if err := json.Unmarshal(rec.Body.Bytes(), &jobs); err != nil {
+			t.Fatalf("Unable to decode %s: %v", rec.Body.String(), err)
+		}
+		got := make([]string, 0, len(jobs))
+		for _, j := range jobs {
+			got = append(got, j.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(test.want, got) {
+			t.Fatalf("%s: Expected: %#v, got: %#v", name, test.want, got)
+		}
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	srv := NewServer(store.NewMemory())
+	extract := decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "extract", "schedule": "0 0 2 * * *", "command": "extract.sh"}`))
+
+	// the dependent job needs no schedule
+	rec := do(srv, http.MethodPost, "/jobs", `{"name": "report", "command": "report.sh", "dependsOn": {"jobs": ["`+extract.ID+`"], "condition": "all_success"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected: %v, got: %v %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	report := decodeJob(t, rec)
+	want := &job.Dependencies{Jobs: []string{extract.ID}, Condition: job.AllSuccess}
+	if !reflect.DeepEqual(want, report.DependsOn) {
+		t.Fatalf("Expected: %#v, got: %#v", want, report.DependsOn)
+	}
+
+	tests := map[string]struct {
+		method string
+		path   string
+		body   string
+		status int
+		field  string
+	}{
+		"unknown job":  {method: http.MethodPost, path: "/jobs", body: `{"name": "other", "command": "x", "dependsOn": {"jobs": ["missing"]}}`, status: http.StatusBadRequest, field: "dependsOn"},
+		"no jobs":      {method: http.MethodPost, path: "/jobs", body: `{"name": "other", "command": "x", "dependsOn": {"jobs": []}}`, status: http.StatusBadRequest, field: "dependsOn"},
+		"no schedule":  {method: http.MethodPost, path: "/jobs", body: `{"name": "other", "command": "x"}`, status: http.StatusBadRequest, field: "schedule"},
+		"cycle":        {method: http.MethodPatch, path: "/jobs/extract", body: `{"dependsOn": {"jobs": ["` + report.ID + `"]}}`, status: http.StatusBadRequest, field: "dependsOn"},
+		"depended on":  {method: http.MethodDelete, path: "/jobs/extract", status: http.StatusConflict},
+		"removed":      {method: http.MethodPatch, path: "/jobs/report", body: `{"dependsOn": null, "schedule": "0 0 3 * * *"}`, status: http.StatusOK},
+		"not depended": {method: http.MethodDelete, path: "/jobs/extract", status: http.StatusNoContent},
+		"itself":       {method: http.MethodPatch, path: "/jobs/report?preview=true", body: `{"dependsOn": {"jobs": ["` + report.ID + `"]}}`, status: http.StatusBadRequest, field: "dependsOn"},
+	}
+
+	// the cases depend on each other
+	for _, name := range []string{"unknown job", "no jobs", "no schedule", "cycle", "depended on", "removed", "not depended", "itself"} {
+		test := tests[name]
+		rec := do(srv, test.method, test.path, test.body)
+		if rec.Code != test.status {
+			t.Fatalf("%s: Expected: %v, got: %v %s", name, test.status, rec.Code, rec.Body.String())
+		}
+		if test.field != "" {
+			if got := decodeError(t, rec); got.Field != test.field {
+				t.Fatalf("%s: Expected: %v, got: %#v", name, test.field, got)
+			}
+		}
+	}
+}
+
+func TestPause(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := NewServer(store.NewMemory())
+	srv.Now = func() time.Time { return now }
+	srv.Scheduler = scheduler.New(scheduler.RealClock{}, scheduler.DispatcherFunc(func(job.Job, job.Run) {}), 3)
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	statuses := func() []job.Status {
+		var runs []job.Run
+		json.Unmarshal(do(srv, http.MethodGet, "/jobs/backup/planned", "").Body.Bytes(), &runs)
+		res := make([]job.Status, 0, len(runs))
+		for _, run := range runs {
+			res = append(res, run.Status)
+		}
+		return res
+	}
+
+	tests := map[string]struct {
+		body   string
+		status int
+		field  string
+	}{
+		"no reason":   {body: `{"actor": "ops"}`, status: http.StatusBadRequest, field: "reason"},
+		"past":        {body: `{"reason": "incident", "until": "2019-12-31T00:00:00Z"}`, status: http.StatusBadRequest, field: "until"},
+		"stale":       {body: `{"reason": "incident", "version": 5}`, status: http.StatusConflict},
+		"unknown":     {body: `{"reason": "incident", "color": "red"}`, status: http.StatusBadRequest},
+		"indefinite":  {body: `{"reason": "incident"}`, status: http.StatusOK},
+		"with expiry": {body: `{"reason": "deploy", "actor": "ops", "until": "2020-01-02T00:00:00Z"}`, status: http.StatusOK},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(srv, http.MethodPost, "/jobs/backup/pause", test.body)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.field != "" && decodeError(t, rec).Field != test.field {
+				t.Fatalf("Expected: %v, got: %s", test.field, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/backup/pause", strings.NewReader(`{"reason": "incident", "until": "2020-01-02T00:00:00Z"}`))
+	req.Header.Set("X-Actor", "alice")
+	paused := decodeJob(t, serveRequest(srv, req))
+	until := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	want := &job.Pause{Reason: "incident", Actor: "alice", At: now, Until: &until}
+	if !reflect.DeepEqual(want, paused.Pause) || paused.Version <= created.Version {
+		t.Fatalf("Expected: %#v, got: %#v", want, paused)
+	}
+
+	// the replaced job stays paused
+	replaced := decodeJob(t, do(srv, http.MethodPut, "/jobs/backup", backup))
+	if !reflect.DeepEqual(want, replaced.Pause) {
+		t.Fatalf("Expected: %#v, got: %#v", want, replaced.Pause)
+	}
+
+	// the planned runs are skipped while the job is paused
+	do(srv, http.MethodPost, "/jobs/backup/pause", `{"reason": "incident"}`)
+	if want := []job.Status{job.StatusSkipped, job.StatusSkipped, job.StatusSkipped}; !reflect.DeepEqual(want, statuses()) {
+		t.Fatalf("Expected: %v, got: %v", want, statuses())
+	}
+
+	for _, body := range []string{"", `{}`} {
+		rec := do(srv, http.MethodPost, "/jobs/backup/resume", body)
+		if resumed := decodeJob(t, rec); rec.Code != http.StatusOK || resumed.Pause != nil {
+			t.Fatalf("Expected the resumed job, got: %v %#v", rec.Code, resumed)
+		}
+	}
+	if want := []job.Status{job.StatusPlanned, job.StatusPlanned, job.StatusPlanned}; !reflect.DeepEqual(want, statuses()) {
+		t.Fatalf("Expected: %v, got: %v", want, statuses())
+	}
+	if rec := do(srv, http.MethodPost, "/jobs/missing/resume", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/store"
+)
+
+// followInterval is the delay between the checks of the followed log
+var followInterval = 500 * time.Millisecond
+
+// runLogs serves the output of the run, either whole (with the support of the Range requests),
+// the last lines with ?tail=<n> or, with ?follow=true, streamed until the run finishes
+func (s *Server) runLogs(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	run, err := s.runs.GetRun(params["id"])
+	if err == store.ErrNotFound {
+		err = errRunNotFound(params["id"])
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if s.Logs == nil {
+		writeError(w, Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "The output of the runs is not kept"})
+		return
+	}
+
+	q := r.URL.Query()
+	tail := -1
+	if v := q.Get("tail"); v != "" {
+		if tail, err = strconv.Atoi(v); err != nil || tail < 0 {
+			writeError(w, errInvalidQuery("tail", "The tail must be a non-negative number of lines"))
+			return
+		}
+	}
+	follow, _ := strconv.ParseBool(q.Get("follow"))
+
+	// the followed log is read with the Cursor surviving its rotations
+	var data []byte
+	var cursor logs.Cursor
+	if follow {
+		data, cursor, err = s.Logs.ReadFrom(run.ID, cursor)
+	} else {
+		data, err = s.Logs.Read(run.ID)
+	}
+	switch {
+	case err == logs.ErrNotFound && follow:
+		data = []byte{}
+	case err == logs.ErrNotFound:
+		writeError(w, Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "No output of the run " + run.ID})
+		return
+	case err != nil:
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if tail < 0 && !follow {
+		http.ServeContent(w, r, "", run.EndTime, bytes.NewReader(data))
+		return
+	}
+
+	if tail >= 0 {
+		data = logs.Tail(data, tail)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+	if follow {
+		s.followLogs(w, r, run, cursor)
+	}
+}
+
+// followLogs writes the output appended after the Cursor until the run finishes or the client leaves
+func (s *Server) followLogs(w http.ResponseWriter, r *http.Request, run job.Run, cursor logs.Cursor) {
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// the status is read first, the output of the finished run is complete then
+		current, err := s.runs.GetRun(run.ID)
+		if err != nil {
+			return
+		}
+		data, next, err := s.Logs.ReadFrom(run.ID, cursor)
+		if err == nil {
+			w.Write(data)
+			cursor = next
+		}
+		if current.Status.Final() {
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(followInterval):
+		}
+	}
+}
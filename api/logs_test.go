@@ -0,0 +1,115 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/store"
+)
+
+func TestRunLogs(t *testing.T) {
+	st := store.NewMemory()
+	srv := NewServer(st)
+	output, _ := logs.OpenDir(t.TempDir())
+	srv.Logs = output
+
+	st.SaveRun(job.Run{ID: "done", JobID: "job", Status: job.StatusSucceeded})
+	st.SaveRun(job.Run{ID: "planned", JobID: "job", Status: job.StatusPlanned})
+	w, _ := output.Create("done")
+	io.WriteString(w, "one\ntwo\nthree\n")
+	w.Close()
+
+	tests := map[string]struct {
+		path   string
+		rng    string
+		status int
+		want   string
+	}{
+		"whole":         {path: "/runs/done/logs", status: http.StatusOK, want: "one\ntwo\nthree\n"},
+		"range":         {path: "/runs/done/logs", rng: "bytes=4-7", status: http.StatusPartialContent, want: "two\n"},
+		"open range":    {path: "/runs/done/logs", rng: "bytes=-6", status: http.StatusPartialContent, want: "three\n"},
+		"tail":          {path: "/runs/done/logs?tail=2", status: http.StatusOK, want: "two\nthree\n"},
+		"follow done":   {path: "/runs/done/logs?follow=true&tail=1", status: http.StatusOK, want: "three\n"},
+		"no output":     {path: "/runs/planned/logs", status: http.StatusNotFound},
+		"unknown run":   {path: "/runs/missing/logs", status: http.StatusNotFound},
+		"invalid tail":  {path: "/runs/done/logs?tail=-1", status: http.StatusBadRequest},
+		"invalid range": {path: "/runs/done/logs", rng: "bytes=100-200", status: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			if test.rng != "" {
+				req.Header.Set("Range", test.rng)
+			}
+			rec := serveRequest(srv, req)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.want != "" && rec.Body.String() != test.want {
+				t.Fatalf("Expected: %q, got: %q", test.want, rec.Body.String())
+			}
+		})
+	}
+
+	// the logs are not available unless kept
+	if rec := do(NewServer(st), http.MethodGet, "/runs/done/logs", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = time.Millisecond
+
+	tests := map[string]struct {
+		maxSize    int64
+		maxBackups int
+	}{
+		"single file": {maxSize: logs.DefaultMaxSize, maxBackups: logs.DefaultMaxBackups},
+		// every line rotates the log while it's followed
+		"rotated": {maxSize: 4, maxBackups: 3},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			st := store.NewMemory()
+			srv := NewServer(st)
+			output, _ := logs.OpenDir(t.TempDir())
+			output.MaxSize, output.MaxBackups = test.maxSize, test.maxBackups
+			srv.Logs = output
+
+			run := job.Run{ID: "run", JobID: "job", Status: job.StatusQueued}
+			st.SaveRun(run)
+
+			done := make(chan string)
+			go func() {
+				done <- do(srv, http.MethodGet, "/runs/run/logs?follow=true", "").Body.String()
+			}()
+
+			w, _ := output.Create("run")
+			run.Transition(job.StatusRunning, time.Now())
+			st.SaveRun(run)
+			for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+				io.WriteString(w, line)
+				time.Sleep(5 * time.Millisecond)
+			}
+			w.Close()
+			run.Transition(job.StatusFailed, time.Now())
+			st.SaveRun(run)
+
+			select {
+			case got := <-done:
+				if want := "one\ntwo\nthree\nfour\n"; got != want {
+					t.Fatalf("Expected: %q, got: %q", want, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Expected the follow to end with the run")
+			}
+		})
+	}
+}
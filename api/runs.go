@@ -0,0 +1,257 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/store"
+)
+
+// runRequest is the body of the request starting a Run, the missing fields keep the ones of the Job
+type runRequest struct {
+	Args   []string          `json:"args"`
+	Config map[string]string `json:"config"`
+}
+
+// Limits of the size of the page of the listed runs
+const (
+	defaultRunLimit = 50
+	maxRunLimit     = 500
+)
+
+func errInvalidQuery(field, message string) error {
+	return Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: message, Field: field}
+}
+
+func errRunNotFound(id string) error {
+	return Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "Run " + id + " not found"}
+}
+
+// queryList collects the values of the repeated or comma-separated query parameter
+func queryList(q url.Values, key string) []string {
+	values := make([]string, 0)
+	for _, v := range q[key] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// queryTime parses the RFC3339 query parameter, the missing one is the zero time
+func queryTime(q url.Values, key string) (time.Time, error) {
+	v := q.Get(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errInvalidQuery(key, "Unable to parse "+key+": "+err.Error())
+	}
+	return t, nil
+}
+
+// runFilter reads the filters of the listed runs from the query:
+// status, trigger, from, to (of the start time), sort (startTime or -startTime), limit and cursor
+func runFilter(q url.Values) (store.RunFilter, error) {
+	f := store.RunFilter{Limit: defaultRunLimit, Cursor: q.Get("cursor")}
+	var err error
+
+	for _, v := range queryList(q, "status") {
+		if !job.Status(v).Valid() {
+			return f, errInvalidQuery("status", "Unknown status "+v)
+		}
+		f.Status = append(f.Status, job.Status(v))
+	}
+	for _, v := range queryList(q, "trigger") {
+		if !job.Trigger(v).Valid() {
+			return f, errInvalidQuery("trigger", "Unknown trigger "+v)
+		}
+		f.Trigger = append(f.Trigger, job.Trigger(v))
+	}
+	if f.From, err = queryTime(q, "from"); err != nil {
+		return f, err
+	}
+	if f.To, err = queryTime(q, "to"); err != nil {
+		return f, err
+	}
+
+	switch q.Get("sort") {
+	case "", "-startTime":
+	case "startTime":
+		f.Ascending = true
+	default:
+		return f, errInvalidQuery("sort", "Unknown sort "+q.Get("sort")+", expected startTime or -startTime")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRunLimit {
+			return f, errInvalidQuery("limit", "The limit must be a number between 1 and "+strconv.Itoa(maxRunLimit))
+		}
+		f.Limit = limit
+	}
+	return f, nil
+}
+
+// writeRuns lists the page of the runs selected by the filter
+func (s *Server) writeRuns(w http.ResponseWriter, f store.RunFilter) {
+	page, err := s.runs.ListRuns(f)
+	if err == store.ErrInvalidCursor {
+		writeError(w, errInvalidQuery("cursor", "The cursor is not valid, use the one of the previous page"))
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// listRuns lists the runs of all the jobs or of the one given by the query parameter job (an ID or a name)
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	f, err := runFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if ref := r.URL.Query().Get("job"); ref != "" {
+		j, err := s.lookupJob(r, ref)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		f.JobID = j.ID
+	}
+	s.writeRuns(w, f)
+}
+
+func (s *Server) listJobRuns(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	f, err := runFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	j, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	f.JobID = j.ID
+	s.writeRuns(w, f)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	run, err := s.runs.GetRun(params["id"])
+	if err == store.ErrNotFound {
+		err = errRunNotFound(params["id"])
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// startRun starts the Run of the Job immediately, the request may override the args and config of the Job for this Run
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req runRequest
+	if err := decodeOptional(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	j, err := s.lookupJob(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if s.Runner == nil {
+		writeError(w, Error{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: "The runs are not executed by this server"})
+		return
+	}
+
+	now := s.Now()
+	run := job.Run{ID: store.NewID(), JobID: j.ID, ScheduledTime: now, Trigger: job.TriggerManual, Attempt: 1}
+	if req.Args != nil || len(req.Config) > 0 {
+		run.Overrides = &job.Overrides{Args: req.Args, Config: req.Config}
+	}
+	run.Transition(job.StatusQueued, now)
+	if err := s.runs.SaveRun(run); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.Runner.Dispatch(j, run)
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// cancelRequest is the body of the request cancelling a Run
+type cancelRequest struct {
+	// Actor defaults to the header X-Actor
+	Actor string `json:"actor"`
+}
+
+// cancelRun stops the running Run and waits until it's stopped, the Run that is only queued is cancelled right away
+// by the Runner, so it can't start in the meantime. The Run unknown to the Runner (e.g. left running
+// by a previous instance of the server) is marked as cancelled.
+func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req cancelRequest
+	if err := decodeOptional(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	actor := requestActor(r, req.Actor)
+
+	run, err := s.runs.GetRun(params["id"])
+	if err == store.ErrNotFound {
+		err = errRunNotFound(params["id"])
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if run.Status.Final() {
+		writeError(w, Error{Status: http.StatusConflict, Code: CodeConflict, Message: "Run " + run.ID + " is " + string(run.Status) + " already"})
+		return
+	}
+
+	if s.Runner != nil {
+		if done, ok := s.Runner.Cancel(run.ID, actor); ok {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+				return
+			}
+			if run, err = s.runs.GetRun(run.ID); err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, run)
+			return
+		}
+	}
+
+	run.CancelledBy = actor
+	if run.Status == job.StatusRunning {
+		run.EndTime = s.Now()
+	}
+	if err := run.Transition(job.StatusCancelled, s.Now()); err != nil {
+		writeError(w, Error{Status: http.StatusConflict, Code: CodeConflict, Message: err.Error()})
+		return
+	}
+	if err := s.runs.SaveRun(run); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
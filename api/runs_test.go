@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/executor"
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/scheduler"
+	"github.com/kubistmi/plango/store"
+)
+
+// runsServer prepares the server with the jobs backup and report, each with three runs an hour apart
+func runsServer(t *testing.T) (*Server, job.Job) {
+	t.Helper()
+	st := store.NewMemory()
+	srv := NewServer(st)
+	backupJob := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+	reportJob := decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "report", "schedule": "0 0 3 * * *", "command": "report.sh"}`))
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []job.Status{job.StatusSucceeded, job.StatusFailed, job.StatusSucceeded}
+	for ix, status := range statuses {
+		for _, j := range []job.Job{backupJob, reportJob} {
+			st.SaveRun(job.Run{
+				ID:        j.Name + string(rune('1'+ix)),
+				JobID:     j.ID,
+				StartTime: start.Add(time.Duration(ix) * time.Hour),
+				Status:    status,
+				Trigger:   job.TriggerSchedule,
+			})
+		}
+	}
+	return srv, backupJob
+}
+
+func decodePage(t *testing.T, rec *httptest.ResponseRecorder) (store.RunPage, []string) {
+	t.Helper()
+	var page store.RunPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Unable to decode %s: %v", rec.Body.String(), err)
+	}
+	ids := make([]string, 0, len(page.Runs))
+	for _, run := range page.Runs {
+		ids = append(ids, run.ID)
+	}
+	return page, ids
+}
+
+func TestListRuns(t *testing.T) {
+	srv, backupJob := runsServer(t)
+
+	tests := map[string]struct {
+		path   string
+		status int
+		want   []string
+		field  string
+	}{
+		"all":         {path: "/runs?sort=startTime", want: []string{"backup1", "report1", "backup2", "report2", "backup3", "report3"}},
+		"newest":      {path: "/runs?limit=2", want: []string{"report3", "backup3"}},
+		"job":         {path: "/jobs/" + backupJob.ID + "/runs", want: []string{"backup3", "backup2", "backup1"}},
+		"job by name": {path: "/jobs/report/runs?status=failed", want: []string{"report2"}},
+		"job filter":  {path: "/runs?job=backup&status=succeeded,failed&sort=startTime", want: []string{"backup1", "backup2", "backup3"}},
+		"time range":  {path: "/runs?from=2020-01-01T01:00:00Z&to=2020-01-01T02:00:00Z", want: []string{"report2", "backup2"}},
+		"trigger":     {path: "/runs?trigger=manual", want: []string{}},
+		"unknown job": {path: "/jobs/missing/runs", status: http.StatusNotFound},
+		"bad status":  {path: "/runs?status=done", status: http.StatusBadRequest, field: "status"},
+		"bad trigger": {path: "/runs?trigger=cron", status: http.StatusBadRequest, field: "trigger"},
+		"bad from":    {path: "/runs?from=yesterday", status: http.StatusBadRequest, field: "from"},
+		"bad sort":    {path: "/runs?sort=name", status: http.StatusBadRequest, field: "sort"},
+		"bad limit":   {path: "/runs?limit=0", status: http.StatusBadRequest, field: "limit"},
+		"bad cursor":  {path: "/runs?cursor=nonsense", status: http.StatusBadRequest, field: "cursor"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(srv, http.MethodGet, test.path, "")
+			if test.status == 0 {
+				test.status = http.StatusOK
+			}
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.status != http.StatusOK {
+				if got := decodeError(t, rec); got.Field != test.field {
+					t.Fatalf("Expected: %v, got: %#v", test.field, got)
+				}
+				return
+			}
+			if _, got := decodePage(t, rec); !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRunPages(t *testing.T) {
+	srv, _ := runsServer(t)
+
+	got := make([]string, 0)
+	path := "/runs?status=succeeded&limit=3"
+	for pages := 1; ; pages++ {
+		page, ids := decodePage(t, do(srv, http.MethodGet, path, ""))
+		got = append(got, ids...)
+		if page.Next == "" {
+			if pages != 2 {
+				t.Fatalf("Expected: 2 pages, got: %v", pages)
+			}
+			break
+		}
+		path = "/runs?status=succeeded&limit=3&cursor=" + url.QueryEscape(page.Next)
+	}
+
+	want := []string{"report3", "backup3", "report1", "backup1"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected: %#v, got: %#v", want, got)
+	}
+
+	rec := do(srv, http.MethodGet, "/runs/backup2", "")
+	var run job.Run
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil || run.Status != job.StatusFailed {
+		t.Fatalf("Unexpected run: %s", rec.Body.String())
+	}
+	if rec := do(srv, http.MethodGet, "/runs/missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+// recordDispatcher keeps the dispatched runs together with their jobs
+type recordDispatcher struct {
+	jobs        []job.Job
+	runs        []job.Run
+	parallelism int
+}
+
+func (d *recordDispatcher) Dispatch(j job.Job, run job.Run) {
+	d.jobs = append(d.jobs, j)
+	d.runs = append(d.runs, run)
+}
+
+func (d *recordDispatcher) Cancel(runID, actor string) (<-chan struct{}, bool) {
+	return nil, false
+}
+
+func (d *recordDispatcher) Backfill(j job.Job, runs []job.Run, parallelism int) {
+	d.jobs = append(d.jobs, j)
+	d.runs = append(d.runs, runs...)
+	d.parallelism = parallelism
+}
+
+func TestStartRun(t *testing.T) {
+	st := store.NewMemory()
+	srv := NewServer(st)
+	created := decodeJob(t, do(srv, http.MethodPost, "/jobs", backup))
+
+	if rec := do(srv, http.MethodPost, "/jobs/backup/runs", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected: %v, got: %v", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	dispatcher := &recordDispatcher{}
+	srv.Runner = dispatcher
+	tests := map[string]struct {
+		body      string
+		status    int
+		overrides *job.Overrides
+	}{
+		"no body":       {body: "", status: http.StatusAccepted},
+		"empty":         {body: `{}`, status: http.StatusAccepted},
+		"args":          {body: `{"args": ["--quick"]}`, status: http.StatusAccepted, overrides: &job.Overrides{Args: []string{"--quick"}}},
+		"config":        {body: `{"config": {"DAY": "monday"}}`, status: http.StatusAccepted, overrides: &job.Overrides{Config: map[string]string{"DAY": "monday"}}},
+		"unknown field": {body: `{"command": "rm"}`, status: http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dispatched := len(dispatcher.runs)
+			rec := do(srv, http.MethodPost, "/jobs/"+created.ID+"/runs", test.body)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.status != http.StatusAccepted {
+				if len(dispatcher.runs) != dispatched {
+					t.Fatalf("Expected no run, got: %#v", dispatcher.runs[dispatched:])
+				}
+				return
+			}
+
+			var got job.Run
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if got.ID == "" || got.JobID != created.ID || got.Trigger != job.TriggerManual || got.Status != job.StatusQueued ||
+				!reflect.DeepEqual(test.overrides, got.Overrides) || rec.Header().Get("Location") != "/runs/"+got.ID {
+				t.Fatalf("Unexpected run: %#v", got)
+			}
+			if saved, err := st.GetRun(got.ID); err != nil || saved.Status != job.StatusQueued {
+				t.Fatalf("Expected the queued run to be saved, got: %#v %v", saved, err)
+			}
+			if len(dispatcher.runs) != dispatched+1 || dispatcher.runs[dispatched].ID != got.ID || dispatcher.jobs[dispatched].ID != created.ID {
+				t.Fatalf("Expected the run to be dispatched, got: %#v", dispatcher.runs)
+			}
+		})
+	}
+
+	if rec := do(srv, http.MethodPost, "/jobs/missing/runs", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected: %v, got: %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	st := store.NewMemory()
+	srv := NewServer(st)
+	now := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+	srv.Now = func() time.Time { return now }
+
+	queued := job.Run{ID: "queued", JobID: "job", Trigger: job.TriggerManual}
+	queued.Transition(job.StatusQueued, now)
+	finished := job.Run{ID: "finished", JobID: "job", Trigger: job.TriggerManual}
+	finished.Transition(job.StatusRunning, now)
+	finished.Transition(job.StatusSucceeded, now)
+	st.SaveRun(queued)
+	st.SaveRun(finished)
+
+	tests := map[string]struct {
+		path   string
+		body   string
+		status int
+		by     string
+	}{
+		"queued":   {path: "/runs/queued/cancel", body: `{"actor": "alice"}`, status: http.StatusOK, by: "alice"},
+		"finished": {path: "/runs/finished/cancel", status: http.StatusConflict},
+		"missing":  {path: "/runs/missing/cancel", status: http.StatusNotFound},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := do(srv, http.MethodPost, test.path, test.body)
+			if rec.Code != test.status {
+				t.Fatalf("Expected: %v, got: %v %s", test.status, rec.Code, rec.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var got job.Run
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if saved, _ := st.GetRun(got.ID); got.Status != job.StatusCancelled || got.CancelledBy != test.by || saved.Status != job.StatusCancelled {
+				t.Fatalf("Unexpected run: %#v", got)
+			}
+		})
+	}
+
+	// the running command is stopped by the runner
+	runner := scheduler.NewRunner(context.Background(), executor.Executor{Grace: time.Second}, st)
+	runner.Done = func(executor.Result, error) {}
+	srv.Runner = runner
+	srv.Now = time.Now
+	decodeJob(t, do(srv, http.MethodPost, "/jobs", `{"name": "slow", "schedule": "0 0 2 * * *", "command": "sleep", "args": ["10"]}`))
+
+	var started job.Run
+	json.Unmarshal(do(srv, http.MethodPost, "/jobs/slow/runs", "").Body.Bytes(), &started)
+	deadline := time.Now().Add(5 * time.Second)
+	for run, _ := st.GetRun(started.ID); run.Status != job.StatusRunning; run, _ = st.GetRun(started.ID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the running run, got: %#v", run)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/runs/"+started.ID+"/cancel", nil)
+	req.Header.Set("X-Actor", "bob")
+	rec := serveRequest(srv, req)
+	var got job.Run
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || got.Status != job.StatusCancelled || got.CancelledBy != "bob" || got.EndTime.IsZero() {
+		t.Fatalf("Unexpected cancel: %v %#v", rec.Code, got)
+	}
+	runner.Wait()
+}
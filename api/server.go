@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/store"
+)
+
+// Scheduler is notified about every stored change of the jobs and provides their planned runs
+type Scheduler interface {
+	Upsert(j job.Job)
+	Remove(id string)
+	Planned(id string) ([]job.Run, bool)
+}
+
+// Dispatcher starts the runs of the jobs, it must not block
+type Dispatcher interface {
+	Dispatch(j job.Job, run job.Run)
+	// Backfill runs the queued runs in their order, at most `parallelism` of them at the same time
+	Backfill(j job.Job, runs []job.Run, parallelism int)
+	// Cancel stops the running run or cancels the queued one, the channel is closed once it's stopped,
+	// false if the run isn't known to the dispatcher
+	Cancel(runID, actor string) (<-chan struct{}, bool)
+}
+
+// noScheduler plans nothing
+type noScheduler struct{}
+
+func (noScheduler) Upsert(job.Job)                   {}
+func (noScheduler) Remove(string)                    {}
+func (noScheduler) Planned(string) ([]job.Run, bool) { return nil, false }
+
+// Server serves the RESTful API of plango
+type Server struct {
+	jobs   store.JobStore
+	runs   store.RunStore
+	routes []route
+
+	// Now returns the current time, it defines the default window of the previews
+	Now func() time.Time
+	// Scheduler follows the changes of the jobs, it plans nothing by default
+	Scheduler Scheduler
+	// Logs provides the output of the runs, if set
+	Logs logs.Store
+	// Runner starts the runs requested through the API, if set
+	Runner Dispatcher
+}
+
+// NewServer prepares the Server managing the jobs and the runs in the store
+func NewServer(st store.Store) *Server {
+	s := &Server{
+		jobs:      st,
+		runs:      st,
+		Now:       time.Now,
+		Scheduler: noScheduler{},
+	}
+
+	s.routes = []route{
+		newRoute("/jobs", map[string]handler{
+			http.MethodGet:  s.listJobs,
+			http.MethodPost: s.createJob,
+		}),
+		newRoute("/jobs/{id}", map[string]handler{
+			http.MethodGet:    s.getJob,
+			http.MethodPut:    s.replaceJob,
+			http.MethodPatch:  s.updateJob,
+			http.MethodDelete: s.deleteJob,
+		}),
+		newRoute("/jobs/{id}/pause", map[string]handler{
+			http.MethodPost: s.pauseJob,
+		}),
+		newRoute("/jobs/{id}/resume", map[string]handler{
+			http.MethodPost: s.resumeJob,
+		}),
+		newRoute("/jobs/{id}/planned", map[string]handler{
+			http.MethodGet: s.plannedRuns,
+		}),
+		newRoute("/jobs/{id}/runs", map[string]handler{
+			http.MethodGet:  s.listJobRuns,
+			http.MethodPost: s.startRun,
+		}),
+		newRoute("/jobs/{id}/backfill", map[string]handler{
+			http.MethodPost: s.backfill,
+		}),
+		newRoute("/runs", map[string]handler{
+			http.MethodGet: s.listRuns,
+		}),
+		newRoute("/runs/{id}", map[string]handler{
+			http.MethodGet: s.getRun,
+		}),
+		newRoute("/runs/{id}/cancel", map[string]handler{
+			http.MethodPost: s.cancelRun,
+		}),
+		newRoute("/runs/{id}/logs", map[string]handler{
+			http.MethodGet: s.runLogs,
+		}),
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(s.routes, w, r)
+}
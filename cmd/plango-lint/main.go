@@ -0,0 +1,105 @@
+// Command plango-lint simulates schedules and reports the definitions that are most likely a mistake.
+//
+// Usage:
+//
+//	plango-lint [flags] "<schedule>" ...
+//
+// Schedules are read from the arguments or, if there are none, one per line from the standard input.
+// The exit status is 1 if any schedule can't be parsed or has a finding.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubistmi/plango/schedule"
+)
+
+func main() {
+	samples := flag.Int("n", 5, "number of fire times reported as an evidence")
+	window := flag.Duration("window", 366*24*time.Hour, "length of the simulation")
+	minInterval := flag.Duration("min-interval", time.Minute, "flag schedules firing more often")
+	rare := flag.Float64("rare", 1, "flag weekDay and monthDay combinations matching fewer days a year")
+	from := flag.String("from", "", "start of the simulation in RFC3339, defaults to now")
+	simulate := flag.Bool("simulate", false, "print the first fire times of every schedule")
+	flag.Parse()
+
+	opts := schedule.LintOptions{
+		Window:          *window,
+		Samples:         *samples,
+		MinInterval:     *minInterval,
+		RareDaysPerYear: *rare,
+	}
+	if *from != "" {
+		start, err := time.ParseInLocation(time.RFC3339, *from, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse -from: %v\n", err)
+			os.Exit(2)
+		}
+		opts.From = start
+	}
+
+	definitions := flag.Args()
+	if len(definitions) == 0 {
+		var err error
+		definitions, err = readLines(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read the standard input: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	failed := false
+	for _, def := range definitions {
+		if !lint(os.Stdout, def, opts, *simulate) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// lint prints the findings of a single schedule and reports whether it is clean
+func lint(w io.Writer, def string, opts schedule.LintOptions, simulate bool) bool {
+	sched, err := schedule.ParseSchedule(def)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", def, err)
+		return false
+	}
+
+	if simulate {
+		times, err := sched.Times(opts.From, time.Time{}, opts.Samples)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %v\n", def, err)
+		}
+		for _, t := range times {
+			fmt.Fprintf(w, "%s: fires at %s\n", def, t.Format(time.RFC3339))
+		}
+	}
+
+	findings := schedule.Lint(sched, opts)
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s: %v\n", def, f)
+		for _, t := range f.Evidence {
+			fmt.Fprintf(w, "\t%s\n", t.Format(time.RFC3339))
+		}
+	}
+	return len(findings) == 0
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
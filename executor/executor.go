@@ -0,0 +1,227 @@
+// Package executor runs the commands of the jobs.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// DefaultGrace is the default time between SIGTERM and SIGKILL of the stopped command
+const DefaultGrace = 10 * time.Second
+
+// DefaultCapture is the default number of the last bytes of stdout and stderr captured in the Result
+const DefaultCapture = 64 << 10
+
+// Executor starts the command of the Job directly, without any shell, and waits for it to finish
+type Executor struct {
+	// Dir is the working directory of the commands with no WorkDir of their own, defaults to the one of plango
+	Dir string
+	// Env is the base environment of the commands, defaults to the environment of plango
+	Env []string
+	// Stdin returns the standard input of the Run, it's called by every Execute so the runs don't share a reader.
+	// Nil (or a nil reader) means no input.
+	Stdin func(j job.Job, run job.Run) io.Reader
+	// Stdout and Stderr receive the whole output of the commands besides the captured one
+	Stdout, Stderr io.Writer
+	// Capture is the number of the last bytes of stdout and stderr kept in the Result, defaults to DefaultCapture.
+	// Negative captures nothing, e.g. when the output is kept by Stdout and Stderr already.
+	Capture int
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+	// Started is called with the running Run once the command starts, if set
+	Started func(run job.Run)
+	// Timeout of the jobs with no timeout of their own, zero means no timeout
+	Timeout time.Duration
+	// Grace is the time the command gets to exit after SIGTERM before it's killed, defaults to DefaultGrace
+	Grace time.Duration
+}
+
+// Result is the finished Run together with the captured output of the command, its tail given by the Capture
+type Result struct {
+	Run    job.Run
+	Stdout []byte
+	Stderr []byte
+}
+
+func (e Executor) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
+
+// environ derives the environment of the command: the base one extended (or overridden) by the variables
+// of the Config of the Job and by the Vars describing the Run
+func (e Executor) environ(j job.Job, vars Vars) []string {
+	env := e.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(append([]string{}, env...), j.Config.Environ()...)
+	return append(env, vars.Environ()...)
+}
+
+// tee captures the output and copies it to the writer, if any
+func tee(buf *tail, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
+
+// capture is the size of the tail of the output captured in the Result
+func (e Executor) capture() int {
+	if e.Capture == 0 {
+		return DefaultCapture
+	}
+	if e.Capture < 0 {
+		return 0
+	}
+	return e.Capture
+}
+
+// tail keeps the last size bytes written to it, the memory it holds stays within twice the size
+type tail struct {
+	size int
+	buf  []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	if t.size <= 0 {
+		return len(p), nil
+	}
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 2*t.size {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.size:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the last size bytes, nil if nothing was written
+func (t *tail) Bytes() []byte {
+	if len(t.buf) > t.size {
+		return t.buf[len(t.buf)-t.size:]
+	}
+	return t.buf
+}
+
+// timeout is the timeout of the Job or the default one
+func (e Executor) timeout(j job.Job) time.Duration {
+	if j.Config.Timeout > 0 {
+		return time.Duration(j.Config.Timeout)
+	}
+	return e.Timeout
+}
+
+// stop terminates the process group of the command and kills it unless it exits within the grace period
+func (e Executor) stop(cmd *exec.Cmd, done <-chan error) error {
+	grace := e.Grace
+	if grace <= 0 {
+		grace = DefaultGrace
+	}
+
+	terminate(cmd)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		kill(cmd)
+		return <-done
+	}
+}
+
+// Execute runs the command of the Job, changed by the Overrides of the Run and with the templates in its Args expanded,
+// in the working directory and as the user given by the Config of the Job, and records its StartTime, EndTime, ExitCode and Status into the Run.
+// The error is returned only if the command (its Args or its user) couldn't be started at all, the Run is failed in that case too,
+// or if the Run can't be started, e.g. it was cancelled already.
+// The command running longer than its timeout is stopped (see stop) and the Run is timed out.
+// The context stops the command too, the Run is cancelled with the context or timed out with its deadline.
+func (e Executor) Execute(ctx context.Context, j job.Job, run job.Run) (Result, error) {
+	stdout, stderr := tail{size: e.capture()}, tail{size: e.capture()}
+	j = run.Overrides.Apply(j)
+	vars := NewVars(j, run)
+	args, argsErr := vars.Expand(j.Args)
+
+	cmd := exec.Command(j.Command, args...)
+	setProcessGroup(cmd)
+	userErr := setUser(cmd, j.Config.User)
+	cmd.Dir = e.Dir
+	if j.Config.WorkDir != "" {
+		cmd.Dir = j.Config.WorkDir
+	}
+	cmd.Env = e.environ(j, vars)
+	if e.Stdin != nil {
+		cmd.Stdin = e.Stdin(j, run)
+	}
+	cmd.Stdout = tee(&stdout, e.Stdout)
+	cmd.Stderr = tee(&stderr, e.Stderr)
+
+	run.JobID = j.ID
+	run.StartTime = e.now()
+	if err := run.Transition(job.StatusRunning, run.StartTime); err != nil {
+		return Result{Run: run}, err
+	}
+
+	err := argsErr
+	if err != nil {
+		err = fmt.Errorf("Unable to expand the args: %v", err)
+	} else if userErr != nil {
+		err = fmt.Errorf("Unable to run as the user %s: %v", j.Config.User, userErr)
+	} else if err = cmd.Start(); err != nil {
+		err = fmt.Errorf("Unable to start the command %s: %v", j.Command, err)
+	}
+	if err != nil {
+		run.EndTime = run.StartTime
+		run.ExitCode = -1
+		run.Transition(job.StatusFailed, run.EndTime)
+		return Result{Run: run}, err
+	}
+	if e.Started != nil {
+		e.Started(run)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var expired <-chan time.Time
+	if timeout := e.timeout(j); timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var stopped job.Status
+	select {
+	case err = <-done:
+	case <-expired:
+		stopped = job.StatusTimedOut
+		err = e.stop(cmd, done)
+	case <-ctx.Done():
+		stopped = job.StatusCancelled
+		if ctx.Err() == context.DeadlineExceeded {
+			stopped = job.StatusTimedOut
+		}
+		err = e.stop(cmd, done)
+	}
+
+	run.EndTime = e.now()
+	run.ExitCode = cmd.ProcessState.ExitCode()
+	status := job.StatusSucceeded
+	switch {
+	case stopped != "":
+		status = stopped
+	case err != nil:
+		status = job.StatusFailed
+	}
+	run.Transition(status, run.EndTime)
+
+	return Result{Run: run, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}, nil
+}
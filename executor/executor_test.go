@@ -0,0 +1,304 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// TestHelperProcess isn't a real test, it's the command started by the other tests:
+//   - `echo <args>` prints the arguments to stdout
+//   - `env <name>` prints the environment variable
+//   - `pwd` prints the working directory
+//   - `cat` copies stdin to stdout
+//   - `fail <code>` prints to stderr and exits with the code
+//   - `sleep <duration>` sleeps
+//   - `trap <duration>` sleeps, but exits with 3 on SIGTERM
+//   - `ignore <duration>` sleeps ignoring SIGTERM
+//   - `spawn <duration>` starts a child sleeping for the duration and sleeps as well
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("PLANGO_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	switch args[0] {
+	case "echo":
+		fmt.Print(strings.Join(args[1:], " "))
+	case "env":
+		fmt.Print(os.Getenv(args[1]))
+	case "pwd":
+		dir, _ := os.Getwd()
+		fmt.Print(dir)
+	case "cat":
+		data, _ := ioutil.ReadAll(os.Stdin)
+		os.Stdout.Write(data)
+	case "fail":
+		code, _ := strconv.Atoi(args[1])
+		fmt.Fprint(os.Stderr, "failed")
+		os.Exit(code)
+	case "sleep":
+		d, _ := time.ParseDuration(args[1])
+		time.Sleep(d)
+	case "trap":
+		terminated := make(chan os.Signal, 1)
+		signal.Notify(terminated, syscall.SIGTERM)
+		d, _ := time.ParseDuration(args[1])
+		select {
+		case <-terminated:
+			fmt.Print("terminated")
+			os.Exit(3)
+		case <-time.After(d):
+		}
+	case "ignore":
+		signal.Ignore(syscall.SIGTERM)
+		d, _ := time.ParseDuration(args[1])
+		time.Sleep(d)
+	case "spawn":
+		child := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--", "sleep", args[1])
+		child.Stdout = os.Stdout
+		child.Start()
+		d, _ := time.ParseDuration(args[1])
+		time.Sleep(d)
+	}
+	os.Exit(0)
+}
+
+// helperJob prepares the Job running TestHelperProcess with the arguments
+func helperJob(args ...string) job.Job {
+	return job.Job{
+		ID:      "job",
+		Name:    "helper",
+		Command: os.Args[0],
+		Args:    append([]string{"-test.run=TestHelperProcess", "--"}, args...),
+		Config:  job.Config{Env: map[string]string{"PLANGO_HELPER_PROCESS": "1"}},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	dir := t.TempDir()
+	current, err := user.Current()
+	if err != nil {
+		t.Fatalf("Unable to find the current user: %v", err)
+	}
+	scheduled := time.Date(2020, 1, 1, 6, 0, 0, 0, time.Local)
+
+	tests := map[string]struct {
+		exec   Executor
+		job    job.Job
+		status job.Status
+		code   int
+		stdout string
+		stderr string
+	}{
+		"success":       {job: helperJob("echo", "hello", "world"), status: job.StatusSucceeded, stdout: "hello world"},
+		"no shell":      {job: helperJob("echo", "$HOME", "|", "cat"), status: job.StatusSucceeded, stdout: "$HOME | cat"},
+		"failure":       {job: helperJob("fail", "3"), status: job.StatusFailed, code: 3, stderr: "failed"},
+		"config as env": {job: func() job.Job { j := helperJob("env", "GREETING"); j.Config.Env["GREETING"] = "hi"; return j }(), status: job.StatusSucceeded, stdout: "hi"},
+		"extra as env": {job: func() job.Job {
+			j := helperJob("env", "GREETING")
+			j.Config.Extra = map[string]string{"GREETING": "hi"}
+			return j
+		}(), status: job.StatusSucceeded, stdout: "hi"},
+		"env over extra": {job: func() job.Job {
+			j := helperJob("env", "GREETING")
+			j.Config.Env["GREETING"] = "hello"
+			j.Config.Extra = map[string]string{"GREETING": "hi"}
+			return j
+		}(), status: job.StatusSucceeded, stdout: "hello"},
+		"base env":     {exec: Executor{Env: []string{"GREETING=hello"}}, job: helperJob("env", "GREETING"), status: job.StatusSucceeded, stdout: "hello"},
+		"scheduled":    {job: helperJob("env", EnvScheduledTime), status: job.StatusSucceeded, stdout: scheduled.Format(time.RFC3339)},
+		"run id":       {job: helperJob("env", EnvRunID), status: job.StatusSucceeded, stdout: "run"},
+		"template":     {job: helperJob("echo", `--date={{ .ScheduledTime.Format "2006-01-02" }}`, "{{ .JobName }}"), status: job.StatusSucceeded, stdout: "--date=2020-01-01 helper"},
+		"working dir":  {exec: Executor{Dir: dir}, job: helperJob("pwd"), status: job.StatusSucceeded, stdout: dir},
+		"job dir":      {exec: Executor{Dir: os.TempDir()}, job: func() job.Job { j := helperJob("pwd"); j.Config.WorkDir = dir; return j }(), status: job.StatusSucceeded, stdout: dir},
+		"current user": {job: func() job.Job { j := helperJob("echo", "hello"); j.Config.User = current.Username; return j }(), status: job.StatusSucceeded, stdout: "hello"},
+		"stdin":        {exec: Executor{Stdin: func(job.Job, job.Run) io.Reader { return strings.NewReader("input") }}, job: helperJob("cat"), status: job.StatusSucceeded, stdout: "input"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.exec.Execute(context.Background(), test.job, job.Run{ID: "run", ScheduledTime: scheduled})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Run.Status != test.status || got.Run.ExitCode != test.code {
+				t.Fatalf("Expected: %v %v, got: %v %v", test.status, test.code, got.Run.Status, got.Run.ExitCode)
+			}
+			if string(got.Stdout) != test.stdout || string(got.Stderr) != test.stderr {
+				t.Fatalf("Expected: %q %q, got: %q %q", test.stdout, test.stderr, got.Stdout, got.Stderr)
+			}
+			if got.Run.ID != "run" || got.Run.JobID != "job" || got.Run.StartTime.IsZero() || got.Run.EndTime.Before(got.Run.StartTime) ||
+				len(got.Run.History) != 2 || got.Run.History[0].To != job.StatusRunning {
+				t.Fatalf("Unexpected run: %#v", got.Run)
+			}
+		})
+	}
+}
+
+func TestExecuteTee(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	exec := Executor{Stdout: &stdout, Stderr: &stderr}
+
+	exec.Execute(context.Background(), helperJob("echo", "hello"), job.Run{})
+	exec.Execute(context.Background(), helperJob("fail", "1"), job.Run{})
+	if stdout.String() != "hello" || stderr.String() != "failed" {
+		t.Fatalf("Expected: hello failed, got: %q %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestExecuteStdin(t *testing.T) {
+	exec := Executor{Stdin: func(j job.Job, run job.Run) io.Reader { return strings.NewReader(run.ID) }}
+
+	for _, id := range []string{"first", "second"} {
+		got, _ := exec.Execute(context.Background(), helperJob("cat"), job.Run{ID: id})
+		if string(got.Stdout) != id {
+			t.Fatalf("Expected: %q, got: %q", id, got.Stdout)
+		}
+	}
+}
+
+func TestExecuteCapture(t *testing.T) {
+	tests := map[string]struct {
+		capture int
+		want    string
+	}{
+		"default": {capture: 0, want: "hello world"},
+		"tail":    {capture: 5, want: "world"},
+		"none":    {capture: -1, want: ""},
+	}
+
+	for name, test := range tests {
+		var stdout bytes.Buffer
+		exec := Executor{Stdout: &stdout, Capture: test.capture}
+		got, err := exec.Execute(context.Background(), helperJob("echo", "hello", "world"), job.Run{})
+		if err != nil || string(got.Stdout) != test.want || stdout.String() != "hello world" {
+			t.Fatalf("%s: Expected: %q, got: %q %q %v", name, test.want, got.Stdout, stdout.String(), err)
+		}
+	}
+
+	buf := tail{size: 4}
+	for _, p := range []string{"ab", "cdefghij", "k", "lm"} {
+		buf.Write([]byte(p))
+	}
+	if string(buf.Bytes()) != "jklm" || len(buf.buf) > 8 {
+		t.Fatalf("Expected: %q, got: %q (holding %v bytes)", "jklm", buf.Bytes(), len(buf.buf))
+	}
+}
+
+func TestExecuteStarted(t *testing.T) {
+	var started job.Run
+	exec := Executor{Started: func(run job.Run) { started = run }}
+
+	got, _ := exec.Execute(context.Background(), helperJob("echo", "hello"), job.Run{ID: "run"})
+	if started.ID != "run" || started.Status != job.StatusRunning || got.Run.Status != job.StatusSucceeded {
+		t.Fatalf("Expected the running run, got: %#v", started)
+	}
+}
+
+func TestExecuteOverrides(t *testing.T) {
+	j := helperJob("env", "GREETING")
+	j.Config.Env["GREETING"] = "hi"
+	run := job.Run{Overrides: &job.Overrides{Config: map[string]string{"GREETING": "hello"}}}
+
+	got, err := Executor{}.Execute(context.Background(), j, run)
+	if err != nil || string(got.Stdout) != "hello" || j.Config.Env["GREETING"] != "hi" {
+		t.Fatalf("Expected: %q, got: %q %v", "hello", got.Stdout, err)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	j := job.Job{Command: "/nonexistent/command"}
+	got, err := Executor{}.Execute(context.Background(), j, job.Run{})
+	if err == nil || got.Run.Status != job.StatusFailed || got.Run.ExitCode != -1 {
+		t.Fatalf("Expected failed run with an error, got: %#v %v", got.Run, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	got, err = Executor{}.Execute(ctx, helperJob("sleep", "10s"), job.Run{})
+	if err != nil || got.Run.Status != job.StatusTimedOut || got.Run.ExitCode != -1 {
+		t.Fatalf("Expected killed run, got: %#v %v", got.Run, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	got, err = Executor{}.Execute(ctx, helperJob("sleep", "10s"), job.Run{})
+	if err != nil || got.Run.Status != job.StatusCancelled || got.Run.ExitCode != -1 {
+		t.Fatalf("Expected cancelled run, got: %#v %v", got.Run, err)
+	}
+
+	got, err = Executor{}.Execute(context.Background(), helperJob("echo", "{{ .Missing }}"), job.Run{})
+	if err == nil || got.Run.Status != job.StatusFailed || got.Run.ExitCode != -1 {
+		t.Fatalf("Expected failed run with an error, got: %#v %v", got.Run, err)
+	}
+
+	unknown := helperJob("echo", "hello")
+	unknown.Config.User = "plango-nonexistent-user"
+	got, err = Executor{}.Execute(context.Background(), unknown, job.Run{})
+	if err == nil || got.Run.Status != job.StatusFailed || got.Run.ExitCode != -1 {
+		t.Fatalf("Expected failed run with an error, got: %#v %v", got.Run, err)
+	}
+
+	finished := job.Run{Status: job.StatusCancelled}
+	got, err = Executor{}.Execute(context.Background(), helperJob("echo", "hello"), finished)
+	if _, ok := err.(job.TransitionError); !ok || got.Run.Status != job.StatusCancelled || len(got.Run.History) != 0 {
+		t.Fatalf("Expected the cancelled run not to start, got: %#v %v", got.Run, err)
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	withTimeout := func(j job.Job, timeout time.Duration) job.Job {
+		j.Config.Timeout = job.Duration(timeout)
+		return j
+	}
+
+	tests := map[string]struct {
+		exec   Executor
+		job    job.Job
+		status job.Status
+		code   int
+		stdout string
+	}{
+		"in time":         {job: withTimeout(helperJob("sleep", "10ms"), time.Minute), status: job.StatusSucceeded},
+		"job timeout":     {job: withTimeout(helperJob("sleep", "1m"), 100*time.Millisecond), status: job.StatusTimedOut, code: -1},
+		"default timeout": {exec: Executor{Timeout: 100 * time.Millisecond}, job: helperJob("sleep", "1m"), status: job.StatusTimedOut, code: -1},
+		"job overrides":   {exec: Executor{Timeout: 100 * time.Millisecond}, job: withTimeout(helperJob("sleep", "200ms"), time.Minute), status: job.StatusSucceeded},
+		"graceful":        {job: withTimeout(helperJob("trap", "1m"), 500*time.Millisecond), status: job.StatusTimedOut, code: 3, stdout: "terminated"},
+		"killed":          {exec: Executor{Grace: 100 * time.Millisecond}, job: withTimeout(helperJob("ignore", "1m"), 500*time.Millisecond), status: job.StatusTimedOut, code: -1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			got, err := test.exec.Execute(context.Background(), test.job, job.Run{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Run.Status != test.status || got.Run.ExitCode != test.code || string(got.Stdout) != test.stdout {
+				t.Fatalf("Expected: %v %v %q, got: %v %v %q", test.status, test.code, test.stdout, got.Run.Status, got.Run.ExitCode, got.Stdout)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Fatalf("Expected the command to be stopped, it took %v", elapsed)
+			}
+		})
+	}
+}
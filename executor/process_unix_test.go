@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+func TestExecuteProcessGroup(t *testing.T) {
+	j := helperJob("spawn", "20s")
+	j.Config.Timeout = job.Duration(200 * time.Millisecond)
+
+	// the child keeps the stdout open, the run would wait for it unless the whole group is stopped
+	start := time.Now()
+	got, _ := Executor{}.Execute(context.Background(), j, job.Run{})
+	if elapsed := time.Since(start); elapsed > 10*time.Second || got.Run.Status != job.StatusTimedOut {
+		t.Fatalf("Expected the group to be stopped, got: %v after %v", got.Run.Status, elapsed)
+	}
+}
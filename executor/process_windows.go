@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, there are no process groups to signal on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the command, Windows has no SIGTERM
+func terminate(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// kill stops the command immediately
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// setUser fails for any user, running the commands as other users isn't supported on Windows
+func setUser(cmd *exec.Cmd, name string) error {
+	if name == "" {
+		return nil
+	}
+	return errors.New("running as another user is not supported on Windows")
+}
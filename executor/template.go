@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// Environment variables describing the Run to the command, see Vars
+const (
+	EnvScheduledTime = "PLANGO_SCHEDULED_TIME"
+	EnvPrevTime      = "PLANGO_PREV_TIME"
+	EnvNextTime      = "PLANGO_NEXT_TIME"
+	EnvJobID         = "PLANGO_JOB_ID"
+	EnvJobName       = "PLANGO_JOB_NAME"
+	EnvRunID         = "PLANGO_RUN_ID"
+	EnvAttempt       = "PLANGO_ATTEMPT"
+)
+
+// Vars describe the Run to the command, they are the data of the templates in the Args of the Job
+// (e.g. `--date={{ .ScheduledTime.Format "2006-01-02" }}`) and are passed as the environment variables as well.
+// ScheduledTime is the logical time of the Run, it's kept by the retries and set to the past fire time by the backfill.
+type Vars struct {
+	ScheduledTime time.Time
+	// PrevTime and NextTime are the fire times of the schedule around ScheduledTime, zero if there is none
+	PrevTime time.Time
+	NextTime time.Time
+	JobID    string
+	JobName  string
+	RunID    string
+	Attempt  int
+}
+
+// NewVars describes the Run of the Job
+func NewVars(j job.Job, run job.Run) Vars {
+	vars := Vars{ScheduledTime: run.ScheduledTime, JobID: j.ID, JobName: j.Name, RunID: run.ID, Attempt: run.AttemptNumber()}
+	if !run.ScheduledTime.IsZero() && j.Schedule.String() != "" {
+		vars.PrevTime, _ = j.Schedule.Prev(run.ScheduledTime)
+		if next, err := j.Schedule.Next(run.ScheduledTime.Add(time.Second)); err == nil {
+			vars.NextTime = next
+		}
+	}
+	return vars
+}
+
+// Environ lists the variables as the environment of the command, the times are formatted as RFC3339
+// and the missing ones are left out
+func (v Vars) Environ() []string {
+	env := make([]string, 0, 7)
+	for _, t := range []struct {
+		key  string
+		time time.Time
+	}{{EnvScheduledTime, v.ScheduledTime}, {EnvPrevTime, v.PrevTime}, {EnvNextTime, v.NextTime}} {
+		if !t.time.IsZero() {
+			env = append(env, t.key+"="+t.time.Format(time.RFC3339))
+		}
+	}
+	return append(env,
+		EnvJobID+"="+v.JobID,
+		EnvJobName+"="+v.JobName,
+		EnvRunID+"="+v.RunID,
+		EnvAttempt+"="+strconv.Itoa(v.Attempt),
+	)
+}
+
+// Expand executes the templates in the arguments, the arguments without any `{{` are kept as they are
+func (v Vars) Expand(args []string) ([]string, error) {
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.Contains(arg, "{{") {
+			res = append(res, arg)
+			continue
+		}
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, v); err != nil {
+			return nil, err
+		}
+		res = append(res, buf.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/schedule"
+)
+
+func TestVars(t *testing.T) {
+	daily, _ := schedule.ParseSchedule("0 0 6 * * *")
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 6, 0, 0, 0, time.Local) }
+	j := job.Job{ID: "id", Name: "report", Schedule: daily}
+
+	tests := map[string]struct {
+		run  job.Run
+		want Vars
+	}{
+		"scheduled": {run: job.Run{ID: "run", ScheduledTime: day(2), Attempt: 1}, want: Vars{ScheduledTime: day(2), PrevTime: day(1), NextTime: day(3), JobID: "id", JobName: "report", RunID: "run", Attempt: 1}},
+		"retry":     {run: job.Run{ID: "retry", ScheduledTime: day(2), Attempt: 3}, want: Vars{ScheduledTime: day(2), PrevTime: day(1), NextTime: day(3), JobID: "id", JobName: "report", RunID: "retry", Attempt: 3}},
+		"manual":    {run: job.Run{ID: "run", ScheduledTime: day(2).Add(time.Hour)}, want: Vars{ScheduledTime: day(2).Add(time.Hour), PrevTime: day(2), NextTime: day(3), JobID: "id", JobName: "report", RunID: "run", Attempt: 1}},
+		"no time":   {run: job.Run{ID: "run"}, want: Vars{JobID: "id", JobName: "report", RunID: "run", Attempt: 1}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := NewVars(j, test.run)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+
+	env := NewVars(j, job.Run{ID: "run"}).Environ()
+	want := []string{"PLANGO_JOB_ID=id", "PLANGO_JOB_NAME=report", "PLANGO_RUN_ID=run", "PLANGO_ATTEMPT=1"}
+	if !reflect.DeepEqual(want, env) {
+		t.Fatalf("Expected: %#v, got: %#v", want, env)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := Vars{ScheduledTime: time.Date(2020, 1, 2, 6, 0, 0, 0, time.UTC), JobName: "report", Attempt: 2}
+
+	tests := map[string]struct {
+		args []string
+		want []string
+		err  bool
+	}{
+		"plain":      {args: []string{"--full", "}}"}, want: []string{"--full", "}}"}},
+		"date":       {args: []string{`--date={{ .ScheduledTime.Format "2006-01-02" }}`}, want: []string{"--date=2020-01-02"}},
+		"fields":     {args: []string{"{{ .JobName }}-{{ .Attempt }}"}, want: []string{"report-2"}},
+		"unknown":    {args: []string{"{{ .Missing }}"}, err: true},
+		"unparsable": {args: []string{"{{ .JobName"}, err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := vars.Expand(test.args)
+			if (err != nil) != test.err || !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v %v, got: %#v %v", test.want, test.err, got, err)
+			}
+		})
+	}
+}
+
+func TestVarsValidated(t *testing.T) {
+	// the Job validates the templates against its own copy of the Vars, keep the fields in sync
+	daily, _ := schedule.ParseSchedule("0 0 6 * * *")
+	fields := reflect.TypeOf(Vars{})
+	for i := 0; i < fields.NumField(); i++ {
+		arg := "{{ ." + fields.Field(i).Name + " }}"
+		j := job.Job{Name: "report", Schedule: daily, Command: "report.sh", Args: []string{arg}}
+		if err := j.Validate(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/kubistmi/plango
 
-go 1.12
+go 1.18
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/oklog/ulid v1.3.1
+)
@@ -0,0 +1,50 @@
+package job
+
+import "fmt"
+
+// ConcurrencyPolicy decides what happens with the new Run of the Job that has MaxParallel Runs running already
+type ConcurrencyPolicy string
+
+// Concurrency policies, the empty one is Allow
+const (
+	// ConcurrencyAllow runs any number of Runs in parallel, unless MaxParallel is set, the excess ones are queued then
+	ConcurrencyAllow ConcurrencyPolicy = "allow"
+	// ConcurrencyForbid skips the new Run
+	ConcurrencyForbid ConcurrencyPolicy = "forbid"
+	// ConcurrencyReplace cancels the oldest running Run and starts the new one
+	ConcurrencyReplace ConcurrencyPolicy = "replace"
+	// ConcurrencyQueue starts the new Run once another one finishes
+	ConcurrencyQueue ConcurrencyPolicy = "queue"
+)
+
+// Concurrency limits the Runs of the Job running at the same time
+type Concurrency struct {
+	Policy ConcurrencyPolicy `json:"policy,omitempty"`
+	// MaxParallel is the number of the Runs running at the same time,
+	// zero means unlimited for the policy allow and a single Run otherwise
+	MaxParallel int `json:"maxParallel,omitempty"`
+}
+
+// Limit is the number of the Runs allowed to run at the same time, zero means no limit
+func (c Concurrency) Limit() int {
+	if c.MaxParallel > 0 {
+		return c.MaxParallel
+	}
+	if c.Policy == "" || c.Policy == ConcurrencyAllow {
+		return 0
+	}
+	return 1
+}
+
+// Validate checks the policy and the limit
+func (c Concurrency) Validate() error {
+	switch c.Policy {
+	case "", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace, ConcurrencyQueue:
+	default:
+		return fmt.Errorf("unknown policy %s, expected allow, forbid, replace or queue", c.Policy)
+	}
+	if c.MaxParallel < 0 {
+		return fmt.Errorf("the maxParallel must not be negative")
+	}
+	return nil
+}
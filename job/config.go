@@ -0,0 +1,141 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config is the runtime configuration of the Job
+type Config struct {
+	// Env are the environment variables of the command, added to the base environment of the executor
+	Env map[string]string `json:"env,omitempty"`
+	// WorkDir is the absolute working directory of the command, the one of the executor if empty
+	WorkDir string `json:"workDir,omitempty"`
+	// User runs the command as another user, the user of plango if empty
+	User string `json:"user,omitempty"`
+	// Timeout of a single run, zero uses the default of the executor
+	Timeout Duration `json:"timeout,omitempty"`
+	// Retry of the failed runs, nil means no retries
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Concurrency of the runs, any number of them may run in parallel by default
+	Concurrency Concurrency `json:"concurrency"`
+	// Labels describe the Job, the jobs can be listed by them
+	Labels map[string]string `json:"labels,omitempty"`
+	// Extra is the free-form configuration, the one of the jobs stored before the Config had its fields.
+	// It's passed to the command as the environment variables, overridden by Env.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// jsonConfig is the Config without its UnmarshalJSON
+type jsonConfig Config
+
+// configFields are the JSON names of the fields of the Config
+var configFields = []string{"env", "workDir", "user", "timeout", "retry", "concurrency", "labels", "extra"}
+
+// UnmarshalJSON decodes the Config strictly, the unknown keys are an error.
+// Only the free-form object of strings with none of the keys of the Config (the config of the jobs stored before)
+// is decoded into the Extra, a single mistyped field can't turn the Config into the environment variables.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var typed jsonConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&typed)
+	if err == nil {
+		*c = Config(typed)
+		return nil
+	}
+
+	var extra map[string]string
+	if json.Unmarshal(data, &extra) != nil {
+		return err
+	}
+	for k := range extra {
+		for _, field := range configFields {
+			// the keys match the fields regardless of the case, the same way encoding/json does
+			if strings.EqualFold(k, field) {
+				return err
+			}
+		}
+	}
+	*c = Config{Extra: extra}
+	return nil
+}
+
+// WithDefaults returns the Config with the defaults filled in, the original Config is left intact
+func (c Config) WithDefaults() Config {
+	if c.Concurrency.Policy == "" {
+		c.Concurrency.Policy = ConcurrencyAllow
+	}
+	if c.Retry != nil && c.Retry.Multiplier == 0 {
+		retry := *c.Retry
+		retry.Multiplier = 2
+		c.Retry = &retry
+	}
+	return c
+}
+
+// Validate checks the fields of the Config, the ValidationError names the field as config.<field>
+func (c Config) Validate() error {
+	for k := range c.Env {
+		if k == "" || strings.Contains(k, "=") {
+			return ValidationError{Field: "config.env", Message: "the names of the variables must not be empty or contain ="}
+		}
+	}
+	if c.WorkDir != "" && !filepath.IsAbs(c.WorkDir) {
+		return ValidationError{Field: "config.workDir", Message: "the working directory must be absolute"}
+	}
+	if c.User != strings.TrimSpace(c.User) {
+		return ValidationError{Field: "config.user", Message: "the user must not contain leading or trailing spaces"}
+	}
+	if c.Timeout < 0 {
+		return ValidationError{Field: "config.timeout", Message: "the timeout must not be negative"}
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return ValidationError{Field: "config.retry", Message: err.Error()}
+	}
+	if err := c.Concurrency.Validate(); err != nil {
+		return ValidationError{Field: "config.concurrency", Message: err.Error()}
+	}
+	for k := range c.Labels {
+		if k == "" || strings.Contains(k, "=") {
+			return ValidationError{Field: "config.labels", Message: "the label keys must not be empty or contain ="}
+		}
+	}
+	for k := range c.Extra {
+		if k == "" || strings.Contains(k, "=") {
+			return ValidationError{Field: "config.extra", Message: "the keys must not be empty or contain ="}
+		}
+	}
+	return nil
+}
+
+// Environ returns the variables of the Extra overridden by the Env as the sorted name=value pairs
+func (c Config) Environ() []string {
+	vars := make(map[string]string, len(c.Extra)+len(c.Env))
+	for k, v := range c.Extra {
+		vars[k] = v
+	}
+	for k, v := range c.Env {
+		vars[k] = v
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
+}
+
+// HasLabel checks whether the Job is labelled by the key with the value
+func (c Config) HasLabel(key, value string) bool {
+	v, ok := c.Labels[key]
+	return ok && v == value
+}
@@ -0,0 +1,104 @@
+package job
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config Config
+		want   error
+	}{
+		"empty":          {config: Config{}, want: nil},
+		"valid":          {config: Config{Env: map[string]string{"A": "1"}, WorkDir: "/srv", User: "backup", Labels: map[string]string{"team": "data"}}, want: nil},
+		"empty env":      {config: Config{Env: map[string]string{"": "1"}}, want: ValidationError{Field: "config.env", Message: "the names of the variables must not be empty or contain ="}},
+		"relative dir":   {config: Config{WorkDir: "srv"}, want: ValidationError{Field: "config.workDir", Message: "the working directory must be absolute"}},
+		"spaced user":    {config: Config{User: " backup"}, want: ValidationError{Field: "config.user", Message: "the user must not contain leading or trailing spaces"}},
+		"invalid retry":  {config: Config{Retry: &RetryPolicy{Jitter: 2}}, want: ValidationError{Field: "config.retry", Message: "the jitter must be between 0 and 1"}},
+		"invalid policy": {config: Config{Concurrency: Concurrency{Policy: "never"}}, want: ValidationError{Field: "config.concurrency", Message: "unknown policy never, expected allow, forbid, replace or queue"}},
+		"invalid label":  {config: Config{Labels: map[string]string{"a=b": "c"}}, want: ValidationError{Field: "config.labels", Message: "the label keys must not be empty or contain ="}},
+		"invalid extra":  {config: Config{Extra: map[string]string{"": "c"}}, want: ValidationError{Field: "config.extra", Message: "the keys must not be empty or contain ="}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.config.Validate()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	retry := &RetryPolicy{MaxAttempts: 3}
+	got := Config{Retry: retry}.WithDefaults()
+	want := Config{Retry: &RetryPolicy{MaxAttempts: 3, Multiplier: 2}, Concurrency: Concurrency{Policy: ConcurrencyAllow}}
+	if !reflect.DeepEqual(want, got) || retry.Multiplier != 0 {
+		t.Fatalf("Expected: %#v, got: %#v", want, got)
+	}
+
+	kept := Config{Retry: &RetryPolicy{Multiplier: 1.5}, Concurrency: Concurrency{Policy: ConcurrencyQueue}}
+	if got := kept.WithDefaults(); !reflect.DeepEqual(kept, got) {
+		t.Fatalf("Expected: %#v, got: %#v", kept, got)
+	}
+}
+
+func TestConfigEnviron(t *testing.T) {
+	config := Config{Env: map[string]string{"B": "env", "C": "3"}, Extra: map[string]string{"A": "1", "B": "extra"}}
+	want := []string{"A=1", "B=env", "C=3"}
+	if got := config.Environ(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected: %#v, got: %#v", want, got)
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	tests := map[string]struct {
+		json string
+		want Job
+	}{
+		"typed": {
+			json: `{"config": {"env": {"A": "1"}, "timeout": "1m", "concurrency": {"policy": "forbid"}, "labels": {"team": "data"}, "extra": {"B": "2"}}}`,
+			want: Job{Config: Config{Env: map[string]string{"A": "1"}, Timeout: Duration(time.Minute), Concurrency: Concurrency{Policy: ConcurrencyForbid}, Labels: map[string]string{"team": "data"}, Extra: map[string]string{"B": "2"}}},
+		},
+		"free-form": {
+			json: `{"config": {"A": "1", "ENVIRONMENT": "prod"}}`,
+			want: Job{Config: Config{Extra: map[string]string{"A": "1", "ENVIRONMENT": "prod"}}},
+		},
+		"moved fields": {
+			json: `{"config": {"A": "1"}, "timeout": "1m", "retry": {"maxAttempts": 2}, "concurrency": {"policy": "queue"}}`,
+			want: Job{Config: Config{Timeout: Duration(time.Minute), Retry: &RetryPolicy{MaxAttempts: 2}, Concurrency: Concurrency{Policy: ConcurrencyQueue}, Extra: map[string]string{"A": "1"}}},
+		},
+		"config first": {
+			json: `{"config": {"timeout": "1h"}, "timeout": "1m"}`,
+			want: Job{Config: Config{Timeout: Duration(time.Hour)}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got Job
+			if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"invalid type": `{"config": {"env": {"A": 1}}}`,
+		"mistyped key": `{"config": {"timeout": "5m", "workDir": "relative", "usr": "bob"}}`,
+		"field string": `{"config": {"A": "1", "env": "prod"}}`,
+	}
+	for name, data := range invalid {
+		var j Job
+		if err := json.Unmarshal([]byte(data), &j); err == nil {
+			t.Fatalf("%s: Expected an error, got: %#v", name, j)
+		}
+	}
+}
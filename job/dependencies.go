@@ -0,0 +1,136 @@
+package job
+
+import "fmt"
+
+// Condition decides which outcomes of the upstream Runs trigger the Run of the dependent Job
+type Condition string
+
+// Conditions of the dependencies, the empty one is AllSuccess
+const (
+	// AllSuccess triggers once all the upstream Runs succeed
+	AllSuccess Condition = "all_success"
+	// AnySuccess triggers once any of the upstream Runs succeeds
+	AnySuccess Condition = "any_success"
+	// AnyFailed triggers once any of the upstream Runs fails or times out
+	AnyFailed Condition = "any_failed"
+	// AllDone triggers once all the upstream Runs finish, whatever their status
+	AllDone Condition = "all_done"
+)
+
+// Dependencies are the upstream Jobs whose Runs trigger the Runs of the Job,
+// the triggered Run shares the logical time (ScheduledTime) of the upstream ones
+type Dependencies struct {
+	// Jobs are the IDs of the upstream Jobs
+	Jobs      []string  `json:"jobs"`
+	Condition Condition `json:"condition,omitempty"`
+}
+
+// Validate checks the condition and the list of the upstream Jobs of the Job with the ID,
+// their existence and the cycles are checked by CheckDependencies
+func (d *Dependencies) Validate(id string) error {
+	if d == nil {
+		return nil
+	}
+	switch d.Condition {
+	case "", AllSuccess, AnySuccess, AnyFailed, AllDone:
+	default:
+		return fmt.Errorf("unknown condition %s, expected all_success, any_success, any_failed or all_done", d.Condition)
+	}
+	if len(d.Jobs) == 0 {
+		return fmt.Errorf("the jobs must not be empty")
+	}
+	seen := make(map[string]bool, len(d.Jobs))
+	for _, upstream := range d.Jobs {
+		switch {
+		case upstream == id:
+			return fmt.Errorf("the job must not depend on itself")
+		case seen[upstream]:
+			return fmt.Errorf("the job %s is listed twice", upstream)
+		}
+		seen[upstream] = true
+	}
+	return nil
+}
+
+// Satisfied checks the Condition against the final statuses of the upstream Runs sharing the logical time,
+// the upstream Jobs missing in the map haven't finished yet
+func (d *Dependencies) Satisfied(finished map[string]Status) bool {
+	succeeded, failed, done := 0, 0, 0
+	for _, upstream := range d.Jobs {
+		status, ok := finished[upstream]
+		if !ok {
+			continue
+		}
+		done++
+		switch status {
+		case StatusSucceeded:
+			succeeded++
+		case StatusFailed, StatusTimedOut:
+			failed++
+		}
+	}
+
+	switch d.Condition {
+	case AnySuccess:
+		return succeeded > 0
+	case AnyFailed:
+		return failed > 0
+	case AllDone:
+		return done == len(d.Jobs)
+	}
+	return succeeded == len(d.Jobs)
+}
+
+// CheckDependencies checks that the upstream Jobs of the Job exist among the others and that the dependencies form no cycle,
+// the Job replaces its stored version in `jobs`
+func CheckDependencies(j Job, jobs []Job) error {
+	if j.DependsOn == nil {
+		return nil
+	}
+
+	upstreams := make(map[string][]string, len(jobs)+1)
+	for _, other := range jobs {
+		if other.ID == j.ID {
+			continue
+		}
+		upstreams[other.ID] = nil
+		if other.DependsOn != nil {
+			upstreams[other.ID] = other.DependsOn.Jobs
+		}
+	}
+	for _, upstream := range j.DependsOn.Jobs {
+		if _, ok := upstreams[upstream]; !ok {
+			return ValidationError{Field: "dependsOn", Message: "unknown job " + upstream}
+		}
+	}
+	if j.ID == "" {
+		// nothing depends on the new Job yet
+		return nil
+	}
+	upstreams[j.ID] = j.DependsOn.Jobs
+
+	// depth-first search for a path leading back to the Job
+	visited := make(map[string]bool)
+	var reaches func(id string) bool
+	reaches = func(id string) bool {
+		if id == j.ID {
+			return true
+		}
+		if visited[id] {
+			return false
+		}
+		visited[id] = true
+		for _, upstream := range upstreams[id] {
+			if reaches(upstream) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, upstream := range j.DependsOn.Jobs {
+		if reaches(upstream) {
+			return ValidationError{Field: "dependsOn", Message: "the dependency on " + upstream + " forms a cycle"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependenciesValidate(t *testing.T) {
+	tests := map[string]struct {
+		deps *Dependencies
+		want string
+	}{
+		"none":      {deps: nil},
+		"valid":     {deps: &Dependencies{Jobs: []string{"a", "b"}, Condition: AnyFailed}},
+		"condition": {deps: &Dependencies{Jobs: []string{"a"}, Condition: "some"}, want: "unknown condition some, expected all_success, any_success, any_failed or all_done"},
+		"empty":     {deps: &Dependencies{}, want: "the jobs must not be empty"},
+		"itself":    {deps: &Dependencies{Jobs: []string{"a", "self"}}, want: "the job must not depend on itself"},
+		"twice":     {deps: &Dependencies{Jobs: []string{"a", "a"}}, want: "the job a is listed twice"},
+	}
+
+	for name, test := range tests {
+		err := test.deps.Validate("self")
+		if (err == nil && test.want != "") || (err != nil && err.Error() != test.want) {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, err)
+		}
+	}
+}
+
+func TestDependenciesSatisfied(t *testing.T) {
+	upstreams := []string{"a", "b"}
+	tests := map[string]struct {
+		condition Condition
+		finished  map[string]Status
+		want      bool
+	}{
+		"all success":         {condition: AllSuccess, finished: map[string]Status{"a": StatusSucceeded, "b": StatusSucceeded}, want: true},
+		"all success pending": {condition: AllSuccess, finished: map[string]Status{"a": StatusSucceeded}, want: false},
+		"all success failed":  {condition: AllSuccess, finished: map[string]Status{"a": StatusSucceeded, "b": StatusFailed}, want: false},
+		"default":             {condition: "", finished: map[string]Status{"a": StatusSucceeded, "b": StatusSucceeded}, want: true},
+		"any success":         {condition: AnySuccess, finished: map[string]Status{"b": StatusSucceeded}, want: true},
+		"any failed":          {condition: AnyFailed, finished: map[string]Status{"a": StatusTimedOut}, want: true},
+		"any failed none":     {condition: AnyFailed, finished: map[string]Status{"a": StatusSucceeded, "b": StatusCancelled}, want: false},
+		"all done":            {condition: AllDone, finished: map[string]Status{"a": StatusFailed, "b": StatusCancelled}, want: true},
+		"all done pending":    {condition: AllDone, finished: map[string]Status{"a": StatusFailed}, want: false},
+	}
+
+	for name, test := range tests {
+		deps := &Dependencies{Jobs: upstreams, Condition: test.condition}
+		if got := deps.Satisfied(test.finished); got != test.want {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, got)
+		}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	dependent := func(id string, upstreams ...string) Job {
+		return Job{ID: id, DependsOn: &Dependencies{Jobs: upstreams}}
+	}
+	// extract <- transform <- load
+	stored := []Job{{ID: "extract"}, dependent("transform", "extract"), dependent("load", "transform")}
+
+	tests := map[string]struct {
+		job  Job
+		want error
+	}{
+		"independent": {job: Job{ID: "extract"}},
+		"new":         {job: dependent("", "load", "extract")},
+		"unknown":     {job: dependent("", "missing"), want: ValidationError{Field: "dependsOn", Message: "unknown job missing"}},
+		"update":      {job: dependent("load", "extract")},
+		"cycle":       {job: dependent("extract", "load"), want: ValidationError{Field: "dependsOn", Message: "the dependency on load forms a cycle"}},
+		"short cycle": {job: dependent("transform", "load"), want: ValidationError{Field: "dependsOn", Message: "the dependency on load forms a cycle"}},
+		"diamond":     {job: dependent("report", "transform", "load")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := CheckDependencies(test.job, stored)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is the time.Duration encoded in JSON as a string, e.g. "1h30m"
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON parses the string accepted by time.ParseDuration
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Unable to decode the duration %s, expected a string such as \"1h30m\"", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("Unable to decode the duration %s: %v", data, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
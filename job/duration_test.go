@@ -0,0 +1,41 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	tests := map[string]struct {
+		json  string
+		want  Duration
+		fails bool
+	}{
+		"hours":    {json: `"1h30m"`, want: Duration(90 * time.Minute)},
+		"seconds":  {json: `"45s"`, want: Duration(45 * time.Second)},
+		"zero":     {json: `"0s"`, want: 0},
+		"number":   {json: `30`, fails: true},
+		"no unit":  {json: `"30"`, fails: true},
+		"not time": {json: `"soon"`, fails: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got Duration
+			err := json.Unmarshal([]byte(test.json), &got)
+			if (err != nil) != test.fails || got != test.want {
+				t.Fatalf("Expected: %v %v, got: %v %v", test.want, test.fails, got, err)
+			}
+			if test.fails {
+				return
+			}
+
+			data, _ := json.Marshal(got)
+			var again Duration
+			if err := json.Unmarshal(data, &again); err != nil || again != got {
+				t.Fatalf("Expected: %v, got: %v %v", got, again, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package job defines the jobs planned by plango and their runs.
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kubistmi/plango/schedule"
+)
+
+// DefaultNamespace is used by the Jobs with no namespace
+const DefaultNamespace = "default"
+
+// Job contains the task definition
+type Job struct {
+	ID string `json:"id"`
+	// Name is unique within the Namespace
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Schedule  schedule.Schedule `json:"schedule"`
+	Active    bool              `json:"active"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	// Config is the runtime configuration: environment, working directory, user, timeout, retries, concurrency and labels
+	Config Config `json:"config"`
+	// Misfire catches up the fires missed during the downtime, they are skipped by default
+	Misfire Misfire `json:"misfire"`
+	// Pause skips the scheduled Runs while it lasts, nil if the Job isn't paused
+	Pause *Pause `json:"pause,omitempty"`
+	// DependsOn triggers the Runs once the Runs of the upstream Jobs finish, the Schedule is optional then
+	DependsOn *Dependencies `json:"dependsOn,omitempty"`
+	// Version is increased by every update of the stored Job
+	Version int `json:"version"`
+}
+
+// jsonJob is the Job without its UnmarshalJSON
+type jsonJob Job
+
+// UnmarshalJSON decodes the Job, the timeout, retry and concurrency of the jobs stored before they moved
+// to the Config are moved there
+func (j *Job) UnmarshalJSON(data []byte) error {
+	var legacy struct {
+		jsonJob
+		Timeout     *Duration    `json:"timeout"`
+		Retry       *RetryPolicy `json:"retry"`
+		Concurrency *Concurrency `json:"concurrency"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	*j = Job(legacy.jsonJob)
+	if legacy.Timeout != nil && j.Config.Timeout == 0 {
+		j.Config.Timeout = *legacy.Timeout
+	}
+	if legacy.Retry != nil && j.Config.Retry == nil {
+		j.Config.Retry = legacy.Retry
+	}
+	if legacy.Concurrency != nil && j.Config.Concurrency == (Concurrency{}) {
+		j.Config.Concurrency = *legacy.Concurrency
+	}
+	return nil
+}
+
+// Run defines the singular execution of the Job
+type Run struct {
+	ID    string `json:"id"`
+	JobID string `json:"jobId"`
+	// ScheduledTime is the fire time of the schedule the Run belongs to, i.e. its logical time
+	ScheduledTime time.Time `json:"scheduledTime"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	// Status is changed only by the Transition
+	Status  Status  `json:"status"`
+	Trigger Trigger `json:"trigger"`
+	// ExitCode of the command, -1 if it didn't exit on its own
+	ExitCode int `json:"exitCode"`
+	// History of the transitions of the Status
+	History []Transition `json:"history,omitempty"`
+	// Overrides of the Job applied to this Run only
+	Overrides *Overrides `json:"overrides,omitempty"`
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int `json:"attempt,omitempty"`
+	// RetryOf is the ID of the first attempt of the retried Run
+	RetryOf string `json:"retryOf,omitempty"`
+	// CancelledBy is who cancelled the Run through the API
+	CancelledBy string `json:"cancelledBy,omitempty"`
+}
+
+// Overrides change the Job for a single Run
+type Overrides struct {
+	// Args replace the arguments of the command unless nil
+	Args []string `json:"args"`
+	// Config is merged into the environment variables of the Job, see Config.Env
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// Apply returns the Job changed by the overrides, the original Job is left intact
+func (o *Overrides) Apply(j Job) Job {
+	if o == nil {
+		return j
+	}
+	if o.Args != nil {
+		j.Args = append([]string{}, o.Args...)
+	}
+	if len(o.Config) > 0 {
+		env := make(map[string]string, len(j.Config.Env)+len(o.Config))
+		for k, v := range j.Config.Env {
+			env[k] = v
+		}
+		for k, v := range o.Config {
+			env[k] = v
+		}
+		j.Config.Env = env
+	}
+	return j
+}
+
+// ValidationError describes the invalid field of the Job
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("Invalid field %s: %s", e.Field, e.Message)
+}
+
+// CreateJob prepares a new Job definition
+// The ID is assigned once the Job is stored.
+func CreateJob(Name string, Plan schedule.Schedule, Command string, Args []string, Config Config) Job {
+	NewJob := Job{
+		Name:      Name,
+		Namespace: DefaultNamespace,
+		Schedule:  Plan,
+		Command:   Command,
+		Args:      Args,
+		Config:    Config,
+	}
+
+	// the runs are planned by the scheduler once the Job is stored
+
+	return (NewJob)
+
+}
+
+// argVars has the fields of executor.Vars, the data of the templates in the Args (the executor imports the job)
+type argVars struct {
+	ScheduledTime time.Time
+	PrevTime      time.Time
+	NextTime      time.Time
+	JobID         string
+	JobName       string
+	RunID         string
+	Attempt       int
+}
+
+// Validate checks that the Job can be scheduled and executed
+func (j Job) Validate() error {
+	if strings.TrimSpace(j.Name) == "" {
+		return ValidationError{Field: "name", Message: "the name must not be empty"}
+	}
+	// the names are used in the paths of the API
+	if strings.Contains(j.Name, "/") {
+		return ValidationError{Field: "name", Message: "the name must not contain /"}
+	}
+	if strings.Contains(j.Namespace, "/") {
+		return ValidationError{Field: "namespace", Message: "the namespace must not contain /"}
+	}
+	if j.Schedule.String() == "" && j.DependsOn == nil {
+		return ValidationError{Field: "schedule", Message: "the schedule must be set"}
+	}
+	if strings.TrimSpace(j.Command) == "" {
+		return ValidationError{Field: "command", Message: "the command must not be empty"}
+	}
+	for _, arg := range j.Args {
+		// the templates are expanded by the executor, they are tried on the zero variables here
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return ValidationError{Field: "args", Message: "unable to parse the template " + arg + ": " + err.Error()}
+		}
+		if err := tmpl.Execute(ioutil.Discard, argVars{}); err != nil {
+			return ValidationError{Field: "args", Message: "unable to execute the template " + arg + ": " + err.Error()}
+		}
+	}
+	if err := j.Config.Validate(); err != nil {
+		return err
+	}
+	if err := j.Misfire.Validate(); err != nil {
+		return ValidationError{Field: "misfire", Message: err.Error()}
+	}
+	if err := j.DependsOn.Validate(j.ID); err != nil {
+		return ValidationError{Field: "dependsOn", Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/schedule"
+)
+
+func TestValidate(t *testing.T) {
+	daily, _ := schedule.ParseSchedule("0 0 6 * * *")
+
+	tests := map[string]struct {
+		job  Job
+		want error
+	}{
+		"valid":            {job: Job{Name: "backup", Schedule: daily, Command: "backup.sh"}, want: nil},
+		"missing name":     {job: Job{Name: " ", Schedule: daily, Command: "backup.sh"}, want: ValidationError{Field: "name", Message: "the name must not be empty"}},
+		"slash in name":    {job: Job{Name: "a/b", Schedule: daily, Command: "backup.sh"}, want: ValidationError{Field: "name", Message: "the name must not contain /"}},
+		"slash in ns":      {job: Job{Name: "backup", Namespace: "a/b", Schedule: daily, Command: "backup.sh"}, want: ValidationError{Field: "namespace", Message: "the namespace must not contain /"}},
+		"missing schedule": {job: Job{Name: "backup", Command: "backup.sh"}, want: ValidationError{Field: "schedule", Message: "the schedule must be set"}},
+		"missing command":  {job: Job{Name: "backup", Schedule: daily}, want: ValidationError{Field: "command", Message: "the command must not be empty"}},
+		"invalid template": {job: Job{Name: "backup", Schedule: daily, Command: "backup.sh", Args: []string{"{{ .RunID"}}, want: ValidationError{Field: "args", Message: "unable to parse the template {{ .RunID: template: arg:1: unclosed action"}},
+		"unknown variable": {job: Job{Name: "backup", Schedule: daily, Command: "backup.sh", Args: []string{"{{ .Foo }}"}}, want: ValidationError{Field: "args", Message: "unable to execute the template {{ .Foo }}: template: arg:1:3: executing \"arg\" at <.Foo>: can't evaluate field Foo in type job.argVars"}},
+		"invalid config":   {job: Job{Name: "backup", Schedule: daily, Command: "backup.sh", Config: Config{Timeout: -1}}, want: ValidationError{Field: "config.timeout", Message: "the timeout must not be negative"}},
+		"only dependent":   {job: Job{Name: "report", Command: "report.sh", DependsOn: &Dependencies{Jobs: []string{"etl"}}}, want: nil},
+		"self dependent":   {job: Job{ID: "report", Name: "report", Command: "report.sh", DependsOn: &Dependencies{Jobs: []string{"report"}}}, want: ValidationError{Field: "dependsOn", Message: "the job must not depend on itself"}},
+		"invalid misfire":  {job: Job{Name: "backup", Schedule: daily, Command: "backup.sh", Misfire: Misfire{Policy: MisfireAll, MaxRuns: -1}}, want: ValidationError{Field: "misfire", Message: "the maxRuns must not be negative"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.job.Validate()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	base := Job{Args: []string{"--full"}, Config: Config{Env: map[string]string{"A": "1", "B": "2"}, WorkDir: "/srv"}}
+
+	tests := map[string]struct {
+		overrides *Overrides
+		want      Job
+	}{
+		"none":       {overrides: nil, want: base},
+		"empty":      {overrides: &Overrides{}, want: base},
+		"args":       {overrides: &Overrides{Args: []string{"--quick"}}, want: Job{Args: []string{"--quick"}, Config: base.Config}},
+		"no args":    {overrides: &Overrides{Args: []string{}}, want: Job{Args: []string{}, Config: base.Config}},
+		"config":     {overrides: &Overrides{Config: map[string]string{"B": "3", "C": "4"}}, want: Job{Args: base.Args, Config: Config{Env: map[string]string{"A": "1", "B": "3", "C": "4"}, WorkDir: "/srv"}}},
+		"everything": {overrides: &Overrides{Args: []string{"x"}, Config: map[string]string{"A": ""}}, want: Job{Args: []string{"x"}, Config: Config{Env: map[string]string{"A": "", "B": "2"}, WorkDir: "/srv"}}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.overrides.Apply(base)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+			if base.Config.Env["B"] != "2" || base.Args[0] != "--full" {
+				t.Fatalf("The original job was changed: %#v", base)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	tests := map[string]struct {
+		concurrency Concurrency
+		want        int
+	}{
+		"default":         {concurrency: Concurrency{}, want: 0},
+		"allow":           {concurrency: Concurrency{Policy: ConcurrencyAllow}, want: 0},
+		"allow limited":   {concurrency: Concurrency{Policy: ConcurrencyAllow, MaxParallel: 3}, want: 3},
+		"forbid":          {concurrency: Concurrency{Policy: ConcurrencyForbid}, want: 1},
+		"queue":           {concurrency: Concurrency{Policy: ConcurrencyQueue}, want: 1},
+		"replace limited": {concurrency: Concurrency{Policy: ConcurrencyReplace, MaxParallel: 2}, want: 2},
+	}
+
+	for name, test := range tests {
+		if got := test.concurrency.Limit(); got != test.want {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, got)
+		}
+	}
+}
+
+func TestMisfire(t *testing.T) {
+	last := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+	now := last.Add(72 * time.Hour)
+
+	tests := map[string]struct {
+		misfire Misfire
+		keep    int
+		since   time.Time
+	}{
+		"default":        {misfire: Misfire{}, keep: 0, since: last.Add(time.Second)},
+		"once":           {misfire: Misfire{Policy: MisfireOnce}, keep: 1, since: last.Add(time.Second)},
+		"all":            {misfire: Misfire{Policy: MisfireAll}, keep: DefaultMaxMisfires, since: last.Add(time.Second)},
+		"all limited":    {misfire: Misfire{Policy: MisfireAll, MaxRuns: 3}, keep: 3, since: last.Add(time.Second)},
+		"deadline":       {misfire: Misfire{Policy: MisfireOnce, Deadline: Duration(time.Hour)}, keep: 1, since: now.Add(-time.Hour)},
+		"deadline older": {misfire: Misfire{Policy: MisfireOnce, Deadline: Duration(100 * time.Hour)}, keep: 1, since: last.Add(time.Second)},
+	}
+
+	for name, test := range tests {
+		if keep, since := test.misfire.Keep(), test.misfire.Since(last, now); keep != test.keep || !since.Equal(test.since) {
+			t.Fatalf("%s: Expected: %v %v, got: %v %v", name, test.keep, test.since, keep, since)
+		}
+	}
+}
+
+func TestPaused(t *testing.T) {
+	at := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+	until := at.Add(time.Hour)
+
+	tests := map[string]struct {
+		pause *Pause
+		time  time.Time
+		want  bool
+	}{
+		"not paused":     {pause: nil, time: at, want: false},
+		"indefinitely":   {pause: &Pause{Reason: "incident", At: at}, time: at.Add(1000 * time.Hour), want: true},
+		"before expiry":  {pause: &Pause{Reason: "incident", At: at, Until: &until}, time: until.Add(-time.Second), want: true},
+		"at expiry":      {pause: &Pause{Reason: "incident", At: at, Until: &until}, time: until, want: false},
+		"after resuming": {pause: &Pause{Reason: "incident", At: at, Until: &until}, time: until.Add(time.Hour), want: false},
+	}
+
+	for name, test := range tests {
+		if got := (Job{Pause: test.pause}).Paused(test.time); got != test.want {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, got)
+		}
+	}
+}
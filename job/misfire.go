@@ -0,0 +1,73 @@
+package job
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxMisfires is the number of the missed fires caught up by the policy all unless set
+const DefaultMaxMisfires = 10
+
+// MisfirePolicy decides what happens with the fire times missed while the service was down
+type MisfirePolicy string
+
+// Misfire policies, the empty one is Skip
+const (
+	// MisfireSkip drops all the missed fires
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireOnce runs the latest missed fire only
+	MisfireOnce MisfirePolicy = "once"
+	// MisfireAll runs every missed fire, up to MaxRuns of the latest ones
+	MisfireAll MisfirePolicy = "all"
+)
+
+// Misfire defines the catch-up of the Job after the downtime of the service
+type Misfire struct {
+	Policy MisfirePolicy `json:"policy,omitempty"`
+	// MaxRuns caps the runs of the policy all, defaults to DefaultMaxMisfires
+	MaxRuns int `json:"maxRuns,omitempty"`
+	// Deadline drops the missed fires older than it, zero means no deadline
+	Deadline Duration `json:"deadline,omitempty"`
+}
+
+// Validate checks the policy and the limits
+func (m Misfire) Validate() error {
+	switch m.Policy {
+	case "", MisfireSkip, MisfireOnce, MisfireAll:
+	default:
+		return fmt.Errorf("unknown policy %s, expected skip, once or all", m.Policy)
+	}
+	if m.MaxRuns < 0 {
+		return fmt.Errorf("the maxRuns must not be negative")
+	}
+	if m.Deadline < 0 {
+		return fmt.Errorf("the deadline must not be negative")
+	}
+	return nil
+}
+
+// Keep is the number of the latest missed fires to run
+func (m Misfire) Keep() int {
+	switch m.Policy {
+	case MisfireOnce:
+		return 1
+	case MisfireAll:
+		if m.MaxRuns > 0 {
+			return m.MaxRuns
+		}
+		return DefaultMaxMisfires
+	}
+	return 0
+}
+
+// Since is the time the missed fires are looked for from, i.e. right after the last recorded fire
+// unless the Deadline is closer to now
+func (m Misfire) Since(last, now time.Time) time.Time {
+	since := last.Add(time.Second)
+	if m.Deadline > 0 {
+		if deadline := now.Add(-time.Duration(m.Deadline)); deadline.After(since) {
+			return deadline
+		}
+	}
+	return since
+}
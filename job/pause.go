@@ -0,0 +1,23 @@
+package job
+
+import "time"
+
+// Pause freezes the scheduled Runs of the Job, e.g. during an incident, until the Job is resumed or the pause expires
+type Pause struct {
+	Reason string `json:"reason"`
+	// Actor is who paused the Job
+	Actor string    `json:"actor,omitempty"`
+	At    time.Time `json:"at"`
+	// Until resumes the Job automatically, nil means the Job stays paused until resumed
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Covers checks whether the pause applies to the fire time
+func (p *Pause) Covers(t time.Time) bool {
+	return p != nil && (p.Until == nil || t.Before(*p.Until))
+}
+
+// Paused checks whether the Job is paused at the time
+func (j Job) Paused(t time.Time) bool {
+	return j.Pause.Covers(t)
+}
@@ -0,0 +1,110 @@
+package job
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RetryPolicy defines how the failed Runs of the Job are retried, every attempt is a Run of its own
+type RetryPolicy struct {
+	// MaxAttempts is the number of all the attempts including the first one, up to 1 means no retries
+	MaxAttempts int `json:"maxAttempts"`
+	// InitialDelay is the delay before the first retry
+	InitialDelay Duration `json:"initialDelay"`
+	// Multiplier increases the delay before every following retry, defaults to 2
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// MaxDelay caps the delay, zero means no cap
+	MaxDelay Duration `json:"maxDelay,omitempty"`
+	// Jitter randomizes the delay by up to the fraction of it in both directions, between 0 and 1
+	Jitter float64 `json:"jitter,omitempty"`
+	// ExitCodes are the exit codes worth retrying, any failure is retried if empty.
+	// The runs that were timed out or didn't start have the exit code -1.
+	ExitCodes []int `json:"exitCodes,omitempty"`
+}
+
+// Validate checks the ranges of the values of the policy
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch {
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("the maxAttempts must not be negative")
+	case p.InitialDelay < 0 || p.MaxDelay < 0:
+		return fmt.Errorf("the delays must not be negative")
+	case p.Multiplier != 0 && p.Multiplier < 1:
+		return fmt.Errorf("the multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("the jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Retryable checks whether the finished Run should be attempted again
+func (p *RetryPolicy) Retryable(run Run) bool {
+	if p == nil || run.AttemptNumber() >= p.MaxAttempts {
+		return false
+	}
+	if run.Status != StatusFailed && run.Status != StatusTimedOut {
+		return false
+	}
+	if len(p.ExitCodes) == 0 {
+		return true
+	}
+	for _, code := range p.ExitCodes {
+		if code == run.ExitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay is the time to wait after the failed attempt before the next one,
+// random returns a number in [0, 1) and randomizes the delay by the Jitter
+func (p *RetryPolicy) Delay(attempt int, random func() float64) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*random()-1)
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// AttemptNumber is the Attempt of the Run, the Runs recorded before the retries were introduced are the first attempts
+func (r Run) AttemptNumber() int {
+	if r.Attempt < 1 {
+		return 1
+	}
+	return r.Attempt
+}
+
+// NextAttempt prepares the retry of the Run: the same logical time and overrides, linked to the original Run
+func (r Run) NextAttempt(id string) Run {
+	original := r.RetryOf
+	if original == "" {
+		original = r.ID
+	}
+	return Run{
+		ID:            id,
+		JobID:         r.JobID,
+		ScheduledTime: r.ScheduledTime,
+		Trigger:       TriggerRetry,
+		Attempt:       r.AttemptNumber() + 1,
+		RetryOf:       original,
+		Overrides:     r.Overrides,
+	}
+}
@@ -0,0 +1,77 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+	codes := &RetryPolicy{MaxAttempts: 3, ExitCodes: []int{75, -1}}
+
+	tests := map[string]struct {
+		policy *RetryPolicy
+		run    Run
+		want   bool
+	}{
+		"no policy":      {policy: nil, run: Run{Status: StatusFailed}, want: false},
+		"failed":         {policy: policy, run: Run{Status: StatusFailed, ExitCode: 1}, want: true},
+		"timed out":      {policy: policy, run: Run{Status: StatusTimedOut, ExitCode: -1}, want: true},
+		"succeeded":      {policy: policy, run: Run{Status: StatusSucceeded}, want: false},
+		"cancelled":      {policy: policy, run: Run{Status: StatusCancelled}, want: false},
+		"second attempt": {policy: policy, run: Run{Status: StatusFailed, Attempt: 2}, want: true},
+		"last attempt":   {policy: policy, run: Run{Status: StatusFailed, Attempt: 3}, want: false},
+		"single attempt": {policy: &RetryPolicy{MaxAttempts: 1}, run: Run{Status: StatusFailed}, want: false},
+		"listed code":    {policy: codes, run: Run{Status: StatusFailed, ExitCode: 75}, want: true},
+		"other code":     {policy: codes, run: Run{Status: StatusFailed, ExitCode: 1}, want: false},
+		"listed timeout": {policy: codes, run: Run{Status: StatusTimedOut, ExitCode: -1}, want: true},
+	}
+
+	for name, test := range tests {
+		if got := test.policy.Retryable(test.run); got != test.want {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, got)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	tests := map[string]struct {
+		policy RetryPolicy
+		random float64
+		want   []time.Duration
+	}{
+		"default multiplier": {policy: RetryPolicy{InitialDelay: Duration(time.Second)}, want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		"constant":           {policy: RetryPolicy{InitialDelay: Duration(time.Second), Multiplier: 1}, want: []time.Duration{time.Second, time.Second, time.Second, time.Second}},
+		"capped":             {policy: RetryPolicy{InitialDelay: Duration(time.Second), Multiplier: 3, MaxDelay: Duration(5 * time.Second)}, want: []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}},
+		"jitter down":        {policy: RetryPolicy{InitialDelay: Duration(time.Second), Jitter: 0.5}, random: 0, want: []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}},
+		"jitter up":          {policy: RetryPolicy{InitialDelay: Duration(time.Second), Jitter: 0.5}, random: 0.75, want: []time.Duration{1250 * time.Millisecond, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second}},
+	}
+
+	for name, test := range tests {
+		got := make([]time.Duration, 0)
+		for attempt := 1; attempt <= 4; attempt++ {
+			got = append(got, test.policy.Delay(attempt, func() float64 { return test.random }))
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Fatalf("%s: Expected: %v, got: %v", name, test.want, got)
+		}
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	at := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+	overrides := &Overrides{Args: []string{"--quick"}}
+	first := Run{ID: "r1", JobID: "job", ScheduledTime: at, Trigger: TriggerManual, Status: StatusFailed, Attempt: 1, Overrides: overrides}
+
+	second := first.NextAttempt("r2")
+	want := Run{ID: "r2", JobID: "job", ScheduledTime: at, Trigger: TriggerRetry, Attempt: 2, RetryOf: "r1", Overrides: overrides}
+	if !reflect.DeepEqual(want, second) {
+		t.Fatalf("Expected: %#v, got: %#v", want, second)
+	}
+
+	third := second.NextAttempt("r3")
+	if third.Attempt != 3 || third.RetryOf != "r1" {
+		t.Fatalf("Expected the third attempt of r1, got: %#v", third)
+	}
+}
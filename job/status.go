@@ -0,0 +1,104 @@
+package job
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status of the Run
+type Status string
+
+// Statuses of the Run, see Run.Transition for the allowed changes
+const (
+	StatusPlanned   Status = "planned"
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusTimedOut  Status = "timed_out"
+	StatusCancelled Status = "cancelled"
+	StatusSkipped   Status = "skipped"
+)
+
+// Trigger is the origin of the Run
+type Trigger string
+
+// Triggers of the Run
+const (
+	TriggerSchedule   Trigger = "schedule"
+	TriggerManual     Trigger = "manual"
+	TriggerAPI        Trigger = "api"
+	TriggerDependency Trigger = "dependency"
+	TriggerRetry      Trigger = "retry"
+	TriggerBackfill   Trigger = "backfill"
+)
+
+// transitions lists the statuses reachable from the status, the new Run has no status
+var transitions = map[Status][]Status{
+	"":            {StatusPlanned, StatusQueued, StatusRunning, StatusSkipped},
+	StatusPlanned: {StatusQueued, StatusRunning, StatusSkipped, StatusCancelled},
+	StatusQueued:  {StatusRunning, StatusSkipped, StatusCancelled},
+	StatusRunning: {StatusSucceeded, StatusFailed, StatusTimedOut, StatusCancelled},
+}
+
+var statuses = []Status{StatusPlanned, StatusQueued, StatusRunning, StatusSucceeded, StatusFailed, StatusTimedOut, StatusCancelled, StatusSkipped}
+
+var triggers = []Trigger{TriggerSchedule, TriggerManual, TriggerAPI, TriggerDependency, TriggerRetry, TriggerBackfill}
+
+// Valid checks that the status is one of the defined ones
+func (s Status) Valid() bool {
+	for _, v := range statuses {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Final checks whether the Run with the status is finished, i.e. no other transition is allowed
+func (s Status) Final() bool {
+	return s.Valid() && len(transitions[s]) == 0
+}
+
+// Valid checks that the trigger is one of the defined ones
+func (t Trigger) Valid() bool {
+	for _, v := range triggers {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition is a single change of the status of the Run
+type Transition struct {
+	From Status    `json:"from"`
+	To   Status    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// TransitionError describes the illegal change of the status
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "new"
+	}
+	return fmt.Sprintf("Illegal transition of the run from %s to %s", from, e.To)
+}
+
+// Transition changes the status of the Run and records it into the History, the illegal changes are rejected
+func (r *Run) Transition(to Status, at time.Time) error {
+	for _, allowed := range transitions[r.Status] {
+		if allowed == to {
+			r.History = append(r.History, Transition{From: r.Status, To: to, At: at})
+			r.Status = to
+			return nil
+		}
+	}
+	return TransitionError{From: r.Status, To: to}
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransition(t *testing.T) {
+	tests := map[string]struct {
+		path []Status
+		want error
+	}{
+		"scheduled":         {path: []Status{StatusPlanned, StatusQueued, StatusRunning, StatusSucceeded}},
+		"manual":            {path: []Status{StatusRunning, StatusFailed}},
+		"timed out":         {path: []Status{StatusPlanned, StatusRunning, StatusTimedOut}},
+		"cancelled planned": {path: []Status{StatusPlanned, StatusCancelled}},
+		"skipped":           {path: []Status{StatusQueued, StatusSkipped}},
+		"unfinished":        {path: []Status{StatusPlanned, StatusSucceeded}, want: TransitionError{From: StatusPlanned, To: StatusSucceeded}},
+		"restarted":         {path: []Status{StatusRunning, StatusFailed, StatusRunning}, want: TransitionError{From: StatusFailed, To: StatusRunning}},
+		"backwards":         {path: []Status{StatusRunning, StatusQueued}, want: TransitionError{From: StatusRunning, To: StatusQueued}},
+		"new finished":      {path: []Status{StatusSucceeded}, want: TransitionError{To: StatusSucceeded}},
+		"unknown":           {path: []Status{"done"}, want: TransitionError{To: "done"}},
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var run Run
+			var got error
+			history := make([]Transition, 0)
+			for ix, to := range test.path {
+				at := start.Add(time.Duration(ix) * time.Minute)
+				from := run.Status
+				if got = run.Transition(to, at); got != nil {
+					break
+				}
+				history = append(history, Transition{From: from, To: to, At: at})
+			}
+
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+			if len(history) > 0 && !reflect.DeepEqual(history, run.History) {
+				t.Fatalf("Expected: %#v, got: %#v", history, run.History)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := map[Status]struct {
+		valid bool
+		final bool
+	}{
+		StatusPlanned:   {valid: true},
+		StatusRunning:   {valid: true},
+		StatusSucceeded: {valid: true, final: true},
+		StatusTimedOut:  {valid: true, final: true},
+		StatusSkipped:   {valid: true, final: true},
+		"":              {},
+		"done":          {},
+	}
+
+	for status, test := range tests {
+		if status.Valid() != test.valid || status.Final() != test.final {
+			t.Fatalf("%q: Expected: %v %v, got: %v %v", status, test.valid, test.final, status.Valid(), status.Final())
+		}
+	}
+	if !TriggerBackfill.Valid() || Trigger("cron").Valid() {
+		t.Fatalf("Expected only the defined triggers to be valid")
+	}
+}
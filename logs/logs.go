@@ -0,0 +1,293 @@
+// Package logs keeps the captured output of the runs.
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when there is no output of the run
+var ErrNotFound = errors.New("log not found")
+
+// Defaults of the Dir
+const (
+	DefaultMaxSize    = 10 << 20
+	DefaultMaxBackups = 1
+)
+
+// Store keeps the combined stdout and stderr of every run
+type Store interface {
+	// Create starts a new log of the run, the writer is safe for concurrent use
+	Create(runID string) (io.WriteCloser, error)
+	// Read returns the whole log of the run kept so far
+	Read(runID string) ([]byte, error)
+	// ReadFrom returns the log of the run written after the Cursor and the Cursor at its end,
+	// the zero Cursor reads the whole log
+	ReadFrom(runID string, cursor Cursor) ([]byte, Cursor, error)
+}
+
+// Cursor is the position within the log of a run, it follows the file it points into through the rotations
+type Cursor struct {
+	set bool
+	// rotation is the number of the rotations of the log before the file the Cursor points into was created
+	rotation int
+	offset   int64
+}
+
+// Dir keeps the log of every run in its own file, <runID>.log.
+// Once the file reaches MaxSize, it's rotated to <runID>.log.1, the older files are shifted up to
+// <runID>.log.<MaxBackups> and the oldest one is removed, i.e. only the tail of a huge output is kept.
+type Dir struct {
+	path string
+	// MaxSize is the size of a single log file, defaults to 10 MiB
+	MaxSize int64
+	// MaxBackups is the number of the rotated files of a run, defaults to 1
+	MaxBackups int
+
+	// mu guards the rotations of the logs written by this Dir, the files are renamed only while it's locked
+	mu        sync.Mutex
+	rotations map[string]int
+}
+
+// OpenDir prepares the Dir store, the directory is created if needed
+func OpenDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to prepare the logs directory %s: %v", path, err)
+	}
+	return &Dir{path: path, MaxSize: DefaultMaxSize, MaxBackups: DefaultMaxBackups}, nil
+}
+
+// file returns the path of the log of the run, backup 0 is the current file
+func (d *Dir) file(runID string, backup int) string {
+	name := runID + ".log"
+	if backup > 0 {
+		name += "." + strconv.Itoa(backup)
+	}
+	return filepath.Join(d.path, name)
+}
+
+func checkID(runID string) error {
+	if runID == "" || strings.ContainsAny(runID, `/\`) || strings.HasPrefix(runID, ".") {
+		return fmt.Errorf("Invalid ID of the run %q", runID)
+	}
+	return nil
+}
+
+// Create starts a new log of the run, the previous one is replaced
+func (d *Dir) Create(runID string) (io.WriteCloser, error) {
+	if err := checkID(runID); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.rotations, runID)
+	for backup := 1; backup <= d.MaxBackups; backup++ {
+		os.Remove(d.file(runID, backup))
+	}
+
+	f, err := os.Create(d.file(runID, 0))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create the log of the run %s: %v", runID, err)
+	}
+	return &writer{dir: d, runID: runID, f: f}, nil
+}
+
+// Read concatenates the rotated files of the run and the current one
+func (d *Dir) Read(runID string) ([]byte, error) {
+	if err := checkID(runID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	found := false
+	data := make([]byte, 0)
+	for backup := d.MaxBackups; backup >= 0; backup-- {
+		part, err := ioutil.ReadFile(d.file(runID, backup))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the log of the run %s: %v", runID, err)
+		}
+		found = true
+		data = append(data, part...)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// ReadFrom returns the output written after the Cursor, i.e. the rest of the file the Cursor points into
+// and the whole files rotated after it. Only the output dropped by the rotations in the meantime is missing.
+func (d *Dir) ReadFrom(runID string, cursor Cursor) ([]byte, Cursor, error) {
+	if err := checkID(runID); err != nil {
+		return nil, cursor, ErrNotFound
+	}
+
+	// the files are opened while they can't be rotated, the opened ones are read regardless of the following rotations
+	d.mu.Lock()
+	rotation := d.rotations[runID]
+	files := make([]*os.File, d.MaxBackups+1)
+	var err error
+	for backup := range files {
+		files[backup], err = os.Open(d.file(runID, backup))
+		if err != nil && !os.IsNotExist(err) {
+			break
+		}
+		err = nil
+	}
+	d.mu.Unlock()
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	if err != nil {
+		return nil, cursor, fmt.Errorf("Unable to read the log of the run %s: %v", runID, err)
+	}
+
+	// from the oldest file unless the Cursor points into one of them
+	start, offset := len(files)-1, int64(0)
+	if backup := rotation - cursor.rotation; cursor.set && backup >= 0 && backup < len(files) && files[backup] != nil {
+		start, offset = backup, cursor.offset
+	}
+
+	found := false
+	data := make([]byte, 0)
+	next := cursor
+	for backup := start; backup >= 0; backup-- {
+		if files[backup] == nil {
+			continue
+		}
+		if _, err := files[backup].Seek(offset, io.SeekStart); err != nil {
+			return nil, cursor, fmt.Errorf("Unable to read the log of the run %s: %v", runID, err)
+		}
+		part, err := ioutil.ReadAll(files[backup])
+		if err != nil {
+			return nil, cursor, fmt.Errorf("Unable to read the log of the run %s: %v", runID, err)
+		}
+		found = true
+		data = append(data, part...)
+		next = Cursor{set: true, rotation: rotation - backup, offset: offset + int64(len(part))}
+		offset = 0
+	}
+	if !found {
+		return nil, cursor, ErrNotFound
+	}
+	return data, next, nil
+}
+
+// writer appends to the current file of the log and rotates it once it's full
+type writer struct {
+	mu    sync.Mutex
+	dir   *Dir
+	runID string
+	f     *os.File
+	size  int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.dir.MaxSize > 0 && w.size >= w.dir.MaxSize {
+			if err := w.rotate(); err != nil {
+				return written, err
+			}
+		}
+
+		chunk := p
+		if w.dir.MaxSize > 0 && int64(len(chunk)) > w.dir.MaxSize-w.size {
+			chunk = chunk[:w.dir.MaxSize-w.size]
+		}
+		n, err := w.f.Write(chunk)
+		written += n
+		w.size += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// rotate shifts the files of the log by one and starts a new current file
+func (w *writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+
+	d := w.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.rotations == nil {
+		d.rotations = make(map[string]int)
+	}
+	d.rotations[w.runID]++
+
+	if d.MaxBackups <= 0 {
+		os.Remove(d.file(w.runID, 0))
+	}
+	for backup := d.MaxBackups; backup > 0; backup-- {
+		err := os.Rename(d.file(w.runID, backup-1), d.file(w.runID, backup))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Unable to rotate the log of the run %s: %v", w.runID, err)
+		}
+	}
+
+	f, err := os.Create(d.file(w.runID, 0))
+	if err != nil {
+		return fmt.Errorf("Unable to rotate the log of the run %s: %v", w.runID, err)
+	}
+	w.f = f
+	w.size = 0
+	return nil
+}
+
+func (w *writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// Tail returns the last n lines of the log, a trailing newline doesn't start a new line
+func Tail(data []byte, n int) []byte {
+	if n <= 0 {
+		return data[len(data):]
+	}
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for ix := end - 1; ix >= 0; ix-- {
+		if data[ix] == '\n' {
+			n--
+			if n == 0 {
+				return data[ix+1:]
+			}
+		}
+	}
+	return data
+}
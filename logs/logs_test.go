@@ -0,0 +1,167 @@
+package logs
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	tests := map[string]struct {
+		maxSize    int64
+		maxBackups int
+		writes     []string
+		want       string
+	}{
+		"small":          {maxSize: 100, maxBackups: 1, writes: []string{"hello ", "world"}, want: "hello world"},
+		"exactly full":   {maxSize: 5, maxBackups: 1, writes: []string{"hello"}, want: "hello"},
+		"rotated":        {maxSize: 4, maxBackups: 1, writes: []string{"abcdefghij"}, want: "efghij"},
+		"more backups":   {maxSize: 4, maxBackups: 2, writes: []string{"abc", "defghij"}, want: "abcdefghij"},
+		"oldest removed": {maxSize: 2, maxBackups: 2, writes: []string{"ab", "cd", "ef", "g"}, want: "cdefg"},
+		"no backups":     {maxSize: 4, maxBackups: 0, writes: []string{"abcdefghij"}, want: "ij"},
+		"unlimited":      {maxSize: 0, maxBackups: 0, writes: []string{"abcdefghij"}, want: "abcdefghij"},
+		"empty":          {maxSize: 4, maxBackups: 1, writes: []string{}, want: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d, err := OpenDir(t.TempDir())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			d.MaxSize, d.MaxBackups = test.maxSize, test.maxBackups
+
+			w, err := d.Create("run")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, s := range test.writes {
+				if n, err := io.WriteString(w, s); err != nil || n != len(s) {
+					t.Fatalf("Expected: %v, got: %v %v", len(s), n, err)
+				}
+			}
+			w.Close()
+
+			got, err := d.Read("run")
+			if err != nil || string(got) != test.want {
+				t.Fatalf("Expected: %q, got: %q %v", test.want, got, err)
+			}
+		})
+	}
+}
+
+func TestDirErrors(t *testing.T) {
+	d, _ := OpenDir(t.TempDir())
+	if _, err := d.Read("missing"); err != ErrNotFound {
+		t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+	}
+	if _, err := d.Read("../secret"); err != ErrNotFound {
+		t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+	}
+	if _, err := d.Create("a/b"); err == nil {
+		t.Fatalf("Expected an error for the invalid ID")
+	}
+
+	// the new log replaces the old one, including its backups
+	d.MaxSize = 2
+	w, _ := d.Create("run")
+	io.WriteString(w, "abcdef")
+	w.Close()
+	w, _ = d.Create("run")
+	io.WriteString(w, "x")
+	if _, err := io.WriteString(w, "y"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w.Close()
+	if _, err := io.WriteString(w, "z"); err == nil {
+		t.Fatalf("Expected an error writing to the closed log")
+	}
+	if got, _ := d.Read("run"); string(got) != "xy" {
+		t.Fatalf("Expected: %q, got: %q", "xy", got)
+	}
+}
+
+func TestTail(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		n    int
+		want string
+	}{
+		"last line":         {data: "a\nb\nc\n", n: 1, want: "c\n"},
+		"two lines":         {data: "a\nb\nc\n", n: 2, want: "b\nc\n"},
+		"no final newline":  {data: "a\nb\nc", n: 2, want: "b\nc"},
+		"more than present": {data: "a\nb\n", n: 5, want: "a\nb\n"},
+		"none":              {data: "a\nb\n", n: 0, want: ""},
+		"empty":             {data: "", n: 3, want: ""},
+	}
+
+	for name, test := range tests {
+		if got := string(Tail([]byte(test.data), test.n)); got != test.want {
+			t.Fatalf("%s: Expected: %q, got: %q", name, test.want, got)
+		}
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	d, _ := OpenDir(t.TempDir())
+	d.MaxSize = 64
+	w, _ := d.Create("run")
+
+	done := make(chan bool)
+	for _, s := range []string{"a", "b"} {
+		go func(s string) {
+			for ix := 0; ix < 100; ix++ {
+				io.WriteString(w, s)
+			}
+			done <- true
+		}(s)
+	}
+	<-done
+	<-done
+	w.Close()
+
+	got, _ := d.Read("run")
+	// 200 bytes fill three files, the last one and the backup are kept
+	if len(got) != 64+8 || strings.Trim(string(got), "ab") != "" {
+		t.Fatalf("Expected the last 72 bytes, got: %q", got)
+	}
+}
+
+func TestDirReadFrom(t *testing.T) {
+	tests := map[string]struct {
+		maxBackups int
+		want       []string
+	}{
+		// every read gets the output written since the previous one, the rotated files included
+		"backups": {maxBackups: 2, want: []string{"ab", "cdefgh", "ij", "klmnop"}},
+		// the rotated file is removed, the rest of it is lost
+		"no backups": {maxBackups: 0, want: []string{"ab", "gh", "ij", "op"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d, _ := OpenDir(t.TempDir())
+			d.MaxSize, d.MaxBackups = 2, test.maxBackups
+			w, _ := d.Create("run")
+			defer w.Close()
+
+			var cursor Cursor
+			for i, s := range []string{"ab", "cdefgh", "ij", "klmnop"} {
+				io.WriteString(w, s)
+				got, next, err := d.ReadFrom("run", cursor)
+				if err != nil || string(got) != test.want[i] {
+					t.Fatalf("Expected: %q, got: %q %v", test.want[i], got, err)
+				}
+				cursor = next
+			}
+			if got, _, err := d.ReadFrom("run", cursor); err != nil || len(got) != 0 {
+				t.Fatalf("Expected nothing new, got: %q %v", got, err)
+			}
+		})
+	}
+
+	d, _ := OpenDir(t.TempDir())
+	if _, _, err := d.ReadFrom("missing", Cursor{}); err != ErrNotFound {
+		t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+	}
+}
@@ -1,65 +1,136 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/kubistmi/plango/schedule"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kubistmi/plango/api"
+	"github.com/kubistmi/plango/executor"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/scheduler"
+	"github.com/kubistmi/plango/store"
 )
 
 const (
-	// NumSchedules defines the number of future runs that are planned and stored
+	// NumSchedules defines the number of future runs that are planned and stored for every job
 	NumSchedules = 5
 )
 
-// Job contains the task definition
-type Job struct {
-	ID       string
-	Name     string
-	Schedule schedule.Schedule
-	Active   bool
-	Command  string
-	Args     []string
-	// TODO: is this needed?
-	Config map[string]string
-}
+// openStore prepares the Store defined as `memory:`, `file:<path>` or `sqlite3:<dsn>`
+func openStore(def string) (store.Store, error) {
+	kind := strings.SplitN(def, ":", 2)
+	if len(kind) != 2 {
+		return nil, fmt.Errorf("Incorrect format of the store, expected <kind>:<location>, got %s", def)
+	}
 
-// Run defines the singular execution of the Job
-type Run struct {
-	ID        string
-	JobID     string
-	StartTime time.Time
-	EndTime   time.Time
-	Status    string
-	Trigger   string
+	switch kind[0] {
+	case "memory":
+		return store.NewMemory(), nil
+	case "file":
+		return store.OpenFile(kind[1])
+	case "sqlite3":
+		db, err := sql.Open("sqlite3", kind[1])
+		if err != nil {
+			return nil, err
+		}
+		return store.OpenSQL(db)
+	}
+	return nil, fmt.Errorf("Unknown kind of the store %s, expected memory, file or sqlite3", kind[0])
 }
 
-// CreateJob prepares a new Job definition
-func CreateJob(Name string, Plan schedule.Schedule, Command string, Args []string, Config map[string]string) Job {
-	// TODO: implement JobID collection
-	NewJob := Job{
-		ID:       "1",
-		Name:     Name,
-		Schedule: Plan,
-		Command:  Command,
-		Args:     Args,
-		Config:   Config,
-	}
+func main() {
+	addr := flag.String("addr", ":8080", "address the RESTful API listens on")
+	storeDef := flag.String("store", "file:plango.json", "where the jobs and runs are stored: memory:, file:<path> or sqlite3:<dsn>")
+	logDir := flag.String("logs", "plango-logs", "directory keeping the output of the runs, empty keeps none")
+	logSize := flag.Int64("log-size", logs.DefaultMaxSize, "size of a single log file of a run, the older output is rotated")
+	timeout := flag.Duration("timeout", 0, "default timeout of the runs, zero means none")
+	grace := flag.Duration("grace", executor.DefaultGrace, "time between SIGTERM and SIGKILL of the stopped runs")
+	drain := flag.Duration("drain", time.Minute, "time the running runs get to finish on shutdown before they are cancelled")
+	flag.Parse()
 
-	// TODO: implement SQL upload
+	st, err := openStore(*storeDef)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// TODO: implement schedule preparation
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// the runs outlive the signal, they are cancelled only if they don't finish within the drain period
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	defer cancelRuns()
 
-	return (NewJob)
+	exec := executor.Executor{Stdout: os.Stdout, Stderr: os.Stderr, Timeout: *timeout, Grace: *grace}
+	runner := scheduler.NewRunner(runCtx, exec, st)
+	srv := api.NewServer(st)
+	if *logDir != "" {
+		output, err := logs.OpenDir(*logDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		output.MaxSize = *logSize
+		runner.Logs = output
+		srv.Logs = output
+	}
 
-}
+	if err := runner.Recover(); err != nil {
+		log.Fatal(err)
+	}
 
-func main() {
-	sched, err := schedule.ParseSchedule("5 33 15 31 10,12 *")
+	sched := scheduler.New(scheduler.RealClock{}, runner, NumSchedules)
+	runner.Finished = sched.Finished
+	sched.Skipped = runner.Skipped
+	sched.Runs = st
+	stored, err := st.List()
 	if err != nil {
-		fmt.Println(err)
+		log.Fatal(err)
+	}
+	sched.Load(stored)
+	if err := sched.CatchUp(st); err != nil {
+		log.Fatal(err)
 	}
+	scheduled := make(chan struct{})
+	go func() {
+		defer close(scheduled)
+		sched.Run(ctx)
+	}()
 
-	now := time.Date(2019, time.Month(11), 30, 17, 0, 0, 1, time.Local)
-	fmt.Println(sched.Next(now))
+	srv.Scheduler = sched
+	srv.Runner = runner
+	httpSrv := &http.Server{Addr: *addr, Handler: srv}
+	go func() {
+		<-ctx.Done()
+		httpSrv.Shutdown(context.Background())
+	}()
+
+	log.Printf("Listening on %s", *addr)
+	if err := httpSrv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	// nothing is dispatched once both the scheduler and the runner stop, the running runs are drained then
+	<-scheduled
+	runner.Stop()
+	drained := make(chan struct{})
+	go func() {
+		runner.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(*drain):
+		log.Printf("Cancelling the runs still running after %v", *drain)
+		cancelRuns()
+		<-drained
+	}
 }
@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExhausted is returned by Next when the schedule won't fire anymore because of its NotAfter bound.
+var ErrExhausted = errors.New("the schedule is exhausted, no time satisfying the schedule is left before NotAfter")
+
+// Bounded returns a copy of the schedule active only between `notBefore` and `notAfter` (both inclusive).
+// Zero value of either of the bounds leaves that side of the window open.
+func (s Schedule) Bounded(notBefore, notAfter time.Time) (Schedule, error) {
+	if !notBefore.IsZero() && !notAfter.IsZero() && notAfter.Before(notBefore) {
+		return Schedule{}, fmt.Errorf("The bounds must be defined with NotBefore <= NotAfter, got %v - %v", notBefore, notAfter)
+	}
+
+	s.NotBefore = notBefore
+	s.NotAfter = notAfter
+	return s, nil
+}
+
+// Within checks whether the time lies in the active window of the schedule.
+func (s Schedule) Within(t time.Time) bool {
+	if !s.NotBefore.IsZero() && t.Before(s.NotBefore) {
+		return false
+	}
+	if !s.NotAfter.IsZero() && t.After(s.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// Exhausted checks whether the schedule can still fire on or after `after`.
+func (s Schedule) Exhausted(after time.Time) bool {
+	_, err := s.Next(after)
+	return err == ErrExhausted
+}
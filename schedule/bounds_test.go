@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBounded(t *testing.T) {
+	dec := time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.Local)
+	mar := time.Date(2027, time.Month(3), 31, 23, 59, 59, 0, time.Local)
+
+	tests := map[string]struct {
+		notBefore time.Time
+		notAfter  time.Time
+		err       error
+	}{
+		"both bounds":   {notBefore: dec, notAfter: mar, err: nil},
+		"only start":    {notBefore: dec, err: nil},
+		"only end":      {notAfter: mar, err: nil},
+		"no bounds":     {err: nil},
+		"same instant":  {notBefore: dec, notAfter: dec, err: nil},
+		"error swapped": {notBefore: mar, notAfter: dec, err: fmt.Errorf("The bounds must be defined with NotBefore <= NotAfter, got %v - %v", mar, dec)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Schedule{}.Bounded(test.notBefore, test.notAfter)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+			if err == nil && (got.NotBefore != test.notBefore || got.NotAfter != test.notAfter) {
+				t.Fatalf("Expected: %v - %v, got: %v - %v", test.notBefore, test.notAfter, got.NotBefore, got.NotAfter)
+			}
+		})
+	}
+}
+
+func TestNextBounds(t *testing.T) {
+
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	seasonal, _ := daily.Bounded(
+		time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.Local),
+		time.Date(2027, time.Month(3), 31, 23, 59, 59, 0, time.Local),
+	)
+	fromDec, _ := daily.Bounded(time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.Local), time.Time{})
+	untilMar, _ := daily.Bounded(time.Time{}, time.Date(2027, time.Month(3), 31, 23, 59, 59, 0, time.Local))
+	exactEnd, _ := daily.Bounded(time.Time{}, time.Date(2027, time.Month(3), 31, 6, 0, 0, 0, time.Local))
+
+	tests := map[string]struct {
+		sched Schedule
+		after time.Time
+		want  time.Time
+		err   error
+	}{
+		"before the window":        {sched: seasonal, after: time.Date(2026, time.Month(10), 19, 12, 0, 0, 0, time.Local), want: time.Date(2026, time.Month(12), 1, 6, 0, 0, 0, time.Local)},
+		"inside the window":        {sched: seasonal, after: time.Date(2027, time.Month(1), 15, 7, 0, 0, 0, time.Local), want: time.Date(2027, time.Month(1), 16, 6, 0, 0, 0, time.Local)},
+		"last fire in the window":  {sched: seasonal, after: time.Date(2027, time.Month(3), 31, 5, 0, 0, 0, time.Local), want: time.Date(2027, time.Month(3), 31, 6, 0, 0, 0, time.Local)},
+		"exhausted":                {sched: seasonal, after: time.Date(2027, time.Month(3), 31, 7, 0, 0, 0, time.Local), want: time.Date(0, 0, 0, 0, 0, 0, 0, time.Local), err: ErrExhausted},
+		"after the window":         {sched: seasonal, after: time.Date(2027, time.Month(6), 1, 0, 0, 0, 0, time.Local), want: time.Date(0, 0, 0, 0, 0, 0, 0, time.Local), err: ErrExhausted},
+		"open end":                 {sched: fromDec, after: time.Date(2030, time.Month(6), 1, 7, 0, 0, 0, time.Local), want: time.Date(2030, time.Month(6), 2, 6, 0, 0, 0, time.Local)},
+		"open start":               {sched: untilMar, after: time.Date(2019, time.Month(6), 1, 5, 0, 0, 0, time.Local), want: time.Date(2019, time.Month(6), 1, 6, 0, 0, 0, time.Local)},
+		"end bound is inclusive":   {sched: exactEnd, after: time.Date(2027, time.Month(3), 31, 6, 0, 0, 0, time.Local), want: time.Date(2027, time.Month(3), 31, 6, 0, 0, 0, time.Local)},
+		"unbounded keeps behavior": {sched: daily, after: time.Date(2027, time.Month(6), 1, 0, 0, 0, 0, time.Local), want: time.Date(2027, time.Month(6), 1, 6, 0, 0, 0, time.Local)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.sched.Next(test.after)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %v, got: %v", test.want, got)
+			}
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+			if exhausted := test.sched.Exhausted(test.after); exhausted != (test.err == ErrExhausted) {
+				t.Fatalf("Expected exhausted: %v, got: %v", test.err == ErrExhausted, exhausted)
+			}
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	seasonal, _ := daily.Bounded(
+		time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.Local),
+		time.Date(2027, time.Month(3), 31, 23, 59, 59, 0, time.Local),
+	)
+
+	tests := map[string]struct {
+		sched Schedule
+		t     time.Time
+		want  bool
+	}{
+		"before":    {sched: seasonal, t: time.Date(2026, time.Month(11), 30, 23, 59, 59, 0, time.Local), want: false},
+		"on start":  {sched: seasonal, t: time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.Local), want: true},
+		"inside":    {sched: seasonal, t: time.Date(2027, time.Month(2), 1, 0, 0, 0, 0, time.Local), want: true},
+		"after":     {sched: seasonal, t: time.Date(2027, time.Month(4), 1, 0, 0, 0, 0, time.Local), want: false},
+		"no bounds": {sched: daily, t: time.Date(1999, time.Month(4), 1, 0, 0, 0, 0, time.Local), want: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.sched.Within(test.t)
+			if got != test.want {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const dayLength = 24 * time.Hour
+
+// Density describes how the fire times of many schedules are spread over the time of the day.
+type Density struct {
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Bucket     time.Duration `json:"bucket"`
+	Histogram  []Bucket      `json:"histogram"`
+	Collisions []Collision   `json:"collisions"`
+}
+
+// Bucket counts the fire times within [Offset, Offset+Density.Bucket) since the midnight of any day.
+type Bucket struct {
+	Offset time.Duration `json:"offset"`
+	Count  int           `json:"count"`
+}
+
+// Collision lists the schedules firing at the same instant.
+type Collision struct {
+	Time  time.Time `json:"time"`
+	Names []string  `json:"names"`
+}
+
+// Analyze folds the fire times of the named schedules between `from` and `to` (both inclusive)
+// onto a single day split into buckets of the given size and finds the `top` instants
+// with the most schedules firing at once, negative `top` keeps all of them.
+func Analyze(schedules map[string]Schedule, from, to time.Time, bucket time.Duration, top int) (Density, error) {
+	if to.Before(from) {
+		return Density{}, fmt.Errorf("The window must be defined with from <= to, got %v - %v", from, to)
+	}
+	if bucket < time.Second || dayLength%bucket != 0 {
+		return Density{}, fmt.Errorf("The bucket must be at least a second and divide the day evenly, got %v", bucket)
+	}
+
+	density := Density{
+		From:       from,
+		To:         to,
+		Bucket:     bucket,
+		Histogram:  make([]Bucket, dayLength/bucket),
+		Collisions: make([]Collision, 0),
+	}
+	for ix := range density.Histogram {
+		density.Histogram[ix].Offset = time.Duration(ix) * bucket
+	}
+
+	// iterate in a stable order so that the names of collisions are sorted
+	names := make([]string, 0, len(schedules))
+	for name := range schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	instants := make(map[int64]*Collision)
+	for _, name := range names {
+		times, err := windowTimes(schedules[name], from, to)
+		if err != nil {
+			return Density{}, fmt.Errorf("%s: %v", name, err)
+		}
+
+		for _, t := range times {
+			offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+			density.Histogram[offset/bucket].Count++
+
+			c, ok := instants[t.Unix()]
+			if !ok {
+				c = &Collision{Time: t}
+				instants[t.Unix()] = c
+			}
+			c.Names = append(c.Names, name)
+		}
+	}
+
+	for _, c := range instants {
+		if len(c.Names) > 1 {
+			density.Collisions = append(density.Collisions, *c)
+		}
+	}
+	sort.Slice(density.Collisions, func(i, j int) bool {
+		ci, cj := density.Collisions[i], density.Collisions[j]
+		if len(ci.Names) != len(cj.Names) {
+			return len(ci.Names) > len(cj.Names)
+		}
+		return ci.Time.Before(cj.Time)
+	})
+	if top >= 0 && len(density.Collisions) > top {
+		density.Collisions = density.Collisions[:top]
+	}
+	return density, nil
+}
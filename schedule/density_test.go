@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	parse := func(sch string) Schedule {
+		s, _ := ParseSchedule(sch)
+		return s
+	}
+	from := time.Date(2019, time.Month(10), 7, 0, 0, 0, 0, time.Local)
+	to := time.Date(2019, time.Month(10), 8, 23, 59, 59, 0, time.Local)
+
+	schedules := map[string]Schedule{
+		"backup":  parse("0 0 2 * * *"),
+		"report":  parse("0 0 2,6 * * *"),
+		"cleanup": parse("0 0 2 * * *"),
+		"hourly":  parse("0 30 * * * *"),
+	}
+
+	got, err := Analyze(schedules, from, to, time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(got.Histogram) != 24 {
+		t.Fatalf("Expected 24 buckets, got: %v", len(got.Histogram))
+	}
+	counts := map[time.Duration]int{2 * time.Hour: 8, 6 * time.Hour: 4, 13 * time.Hour: 2}
+	for _, b := range got.Histogram {
+		want, ok := counts[b.Offset]
+		if !ok {
+			want = 2
+		}
+		if b.Count != want {
+			t.Fatalf("Expected %v fire times at %v, got: %v", want, b.Offset, b.Count)
+		}
+	}
+
+	collisions := []Collision{
+		{Time: time.Date(2019, time.Month(10), 7, 2, 0, 0, 0, time.Local), Names: []string{"backup", "cleanup", "report"}},
+		{Time: time.Date(2019, time.Month(10), 8, 2, 0, 0, 0, time.Local), Names: []string{"backup", "cleanup", "report"}},
+	}
+	if !reflect.DeepEqual(collisions, got.Collisions) {
+		t.Fatalf("Expected: %v, got: %v", collisions, got.Collisions)
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	from := time.Date(2019, time.Month(10), 7, 0, 0, 0, 0, time.Local)
+
+	tests := map[string]struct {
+		from   time.Time
+		to     time.Time
+		bucket time.Duration
+		err    error
+	}{
+		"minute buckets":    {from: from, to: from.AddDate(0, 0, 1), bucket: time.Minute, err: nil},
+		"error window":      {from: from, to: from.Add(-time.Hour), bucket: time.Hour, err: fmt.Errorf("The window must be defined with from <= to, got %v - %v", from, from.Add(-time.Hour))},
+		"error tiny bucket": {from: from, to: from.AddDate(0, 0, 1), bucket: time.Millisecond, err: fmt.Errorf("The bucket must be at least a second and divide the day evenly, got %v", time.Millisecond)},
+		"error odd bucket":  {from: from, to: from.AddDate(0, 0, 1), bucket: 7 * time.Hour, err: fmt.Errorf("The bucket must be at least a second and divide the day evenly, got %v", 7*time.Hour)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Analyze(map[string]Schedule{"daily": daily}, test.from, test.to, test.bucket, 10)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+		})
+	}
+}
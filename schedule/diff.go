@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxDiffTimes caps the number of fire times of a single schedule inspected by Compare and Analyze
+const MaxDiffTimes = 100000
+
+// Diff describes how the fire times change when a schedule is replaced by another one.
+type Diff struct {
+	From      time.Time   `json:"from"`
+	To        time.Time   `json:"to"`
+	Added     []time.Time `json:"added"`
+	Removed   []time.Time `json:"removed"`
+	Unchanged int         `json:"unchanged"`
+	Before    Summary     `json:"before"`
+	After     Summary     `json:"after"`
+}
+
+// Summary describes the frequency of a schedule within the compared window.
+type Summary struct {
+	Schedule string  `json:"schedule"`
+	Count    int     `json:"count"`
+	PerDay   float64 `json:"perDay"`
+}
+
+// Compare lists the fire times added and removed between `from` and `to` (both inclusive)
+// when the schedule `before` is replaced by `after`.
+func Compare(before, after Schedule, from, to time.Time) (Diff, error) {
+	if to.Before(from) {
+		return Diff{}, fmt.Errorf("The window must be defined with from <= to, got %v - %v", from, to)
+	}
+
+	beforeTimes, err := windowTimes(before, from, to)
+	if err != nil {
+		return Diff{}, err
+	}
+	afterTimes, err := windowTimes(after, from, to)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	diff := Diff{
+		From:    from,
+		To:      to,
+		Added:   make([]time.Time, 0),
+		Removed: make([]time.Time, 0),
+		Before:  summarize(before, beforeTimes, from, to),
+		After:   summarize(after, afterTimes, from, to),
+	}
+
+	// both lists are sorted, merge them
+	ib, ia := 0, 0
+	for ib < len(beforeTimes) || ia < len(afterTimes) {
+		switch {
+		case ia == len(afterTimes) || (ib < len(beforeTimes) && beforeTimes[ib].Before(afterTimes[ia])):
+			diff.Removed = append(diff.Removed, beforeTimes[ib])
+			ib++
+		case ib == len(beforeTimes) || afterTimes[ia].Before(beforeTimes[ib]):
+			diff.Added = append(diff.Added, afterTimes[ia])
+			ia++
+		default:
+			diff.Unchanged++
+			ib++
+			ia++
+		}
+	}
+	return diff, nil
+}
+
+func windowTimes(s Schedule, from, to time.Time) ([]time.Time, error) {
+	times, err := s.Times(from, to, MaxDiffTimes+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) > MaxDiffTimes {
+		return nil, fmt.Errorf("The schedule %s fires more than %v times in the window, use a shorter one", s, MaxDiffTimes)
+	}
+	return times, nil
+}
+
+func summarize(s Schedule, times []time.Time, from, to time.Time) Summary {
+	sum := Summary{Schedule: s.String(), Count: len(times)}
+	if days := to.Sub(from).Hours() / 24; days > 0 {
+		sum.PerDay = float64(len(times)) / days
+	}
+	return sum
+}
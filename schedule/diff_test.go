@@ -0,0 +1,75 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2019, time.Month(10), d, h, 0, 0, 0, time.Local) }
+	parse := func(sch string) Schedule {
+		s, _ := ParseSchedule(sch)
+		return s
+	}
+
+	tests := map[string]struct {
+		before    Schedule
+		after     Schedule
+		from      time.Time
+		to        time.Time
+		added     []time.Time
+		removed   []time.Time
+		unchanged int
+		err       error
+	}{
+		"same schedule":  {before: parse("0 0 6 * * *"), after: parse("0 0 6 * * *"), from: day(7, 0), to: day(9, 23), added: []time.Time{}, removed: []time.Time{}, unchanged: 3},
+		"moved hour":     {before: parse("0 0 6 * * *"), after: parse("0 0 7 * * *"), from: day(7, 0), to: day(8, 23), added: []time.Time{day(7, 7), day(8, 7)}, removed: []time.Time{day(7, 6), day(8, 6)}},
+		"added hour":     {before: parse("0 0 6 * * *"), after: parse("0 0 6,18 * * *"), from: day(7, 0), to: day(8, 23), added: []time.Time{day(7, 18), day(8, 18)}, removed: []time.Time{}, unchanged: 2},
+		"weekdays only":  {before: parse("0 0 6 * * *"), after: parse("0 0 6 * * 1-5"), from: day(4, 0), to: day(7, 23), added: []time.Time{}, removed: []time.Time{day(5, 6), day(6, 6)}, unchanged: 2},
+		"error window":   {before: parse("0 0 6 * * *"), after: parse("0 0 6 * * *"), from: day(8, 0), to: day(7, 0), err: fmt.Errorf("The window must be defined with from <= to, got %v - %v", day(8, 0), day(7, 0))},
+		"error too many": {before: parse("* * * * * *"), after: parse("0 0 6 * * *"), from: day(1, 0), to: day(8, 0), err: fmt.Errorf("The schedule %s fires more than %v times in the window, use a shorter one", "* * * * * *", MaxDiffTimes)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Compare(test.before, test.after, test.from, test.to)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(test.added, got.Added) {
+				t.Fatalf("Expected added: %v, got: %v", test.added, got.Added)
+			}
+			if !reflect.DeepEqual(test.removed, got.Removed) {
+				t.Fatalf("Expected removed: %v, got: %v", test.removed, got.Removed)
+			}
+			if test.unchanged != got.Unchanged {
+				t.Fatalf("Expected unchanged: %v, got: %v", test.unchanged, got.Unchanged)
+			}
+			if got.Before.Count != got.Unchanged+len(got.Removed) || got.After.Count != got.Unchanged+len(got.Added) {
+				t.Fatalf("Summary doesn't add up: %#v", got)
+			}
+		})
+	}
+}
+
+func TestCompareSummary(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	twice, _ := ParseSchedule("0 0 6,18 * * *")
+	from := time.Date(2019, time.Month(10), 1, 0, 0, 0, 0, time.Local)
+
+	got, err := Compare(daily, twice, from, from.AddDate(0, 0, 10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := Diff{}
+	want.Before = Summary{Schedule: "0 0 6 * * *", Count: 10, PerDay: 1}
+	want.After = Summary{Schedule: "0 0 6,18 * * *", Count: 20, PerDay: 2}
+	if !reflect.DeepEqual(want.Before, got.Before) || !reflect.DeepEqual(want.After, got.After) {
+		t.Fatalf("Expected: %#v %#v, got: %#v %#v", want.Before, want.After, got.Before, got.After)
+	}
+}
@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonBounded is the JSON representation of a schedule with bounds
+type jsonBounded struct {
+	Expression string     `json:"expression"`
+	NotBefore  *time.Time `json:"notBefore,omitempty"`
+	NotAfter   *time.Time `json:"notAfter,omitempty"`
+}
+
+// MarshalJSON encodes the schedule as its definition, e.g. "0 30 12 * * *".
+// The bounded schedule is encoded as an object with the fields expression, notBefore and notAfter.
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	if s.NotBefore.IsZero() && s.NotAfter.IsZero() {
+		return json.Marshal(s.String())
+	}
+
+	b := jsonBounded{Expression: s.String()}
+	if !s.NotBefore.IsZero() {
+		b.NotBefore = &s.NotBefore
+	}
+	if !s.NotAfter.IsZero() {
+		b.NotAfter = &s.NotAfter
+	}
+	return json.Marshal(b)
+}
+
+// UnmarshalJSON parses the schedule from its definition or from an object with the bounds, see MarshalJSON.
+// The empty definition is decoded as the empty Schedule, which never fires.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var b jsonBounded
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &b.Expression); err != nil {
+		return err
+	}
+
+	// the empty definition is the empty Schedule, e.g. of a job triggered only by its dependencies
+	if b.Expression == "" && b.NotBefore == nil && b.NotAfter == nil {
+		*s = Schedule{}
+		return nil
+	}
+
+	sched, err := ParseSchedule(b.Expression)
+	if err != nil {
+		return err
+	}
+
+	var notBefore, notAfter time.Time
+	if b.NotBefore != nil {
+		notBefore = *b.NotBefore
+	}
+	if b.NotAfter != nil {
+		notAfter = *b.NotAfter
+	}
+	sched, err = sched.Bounded(notBefore, notAfter)
+	if err != nil {
+		return err
+	}
+
+	*s = sched
+	return nil
+}
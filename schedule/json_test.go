@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	seasonal, _ := daily.Bounded(time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.UTC), time.Date(2027, time.Month(3), 31, 0, 0, 0, 0, time.UTC))
+	fromDec, _ := daily.Bounded(time.Date(2026, time.Month(12), 1, 0, 0, 0, 0, time.UTC), time.Time{})
+
+	tests := map[string]struct {
+		sched Schedule
+		want  string
+	}{
+		"plain":       {sched: daily, want: `"0 0 6 * * *"`},
+		"bounded":     {sched: seasonal, want: `{"expression":"0 0 6 * * *","notBefore":"2026-12-01T00:00:00Z","notAfter":"2027-03-31T00:00:00Z"}`},
+		"only before": {sched: fromDec, want: `{"expression":"0 0 6 * * *","notBefore":"2026-12-01T00:00:00Z"}`},
+		"empty":       {sched: Schedule{}, want: `""`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := json.Marshal(test.sched)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != test.want {
+				t.Fatalf("Expected: %s, got: %s", test.want, got)
+			}
+
+			var back Schedule
+			if err := json.Unmarshal(got, &back); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(test.sched, back) {
+				t.Fatalf("Expected: %#v, got: %#v", test.sched, back)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		err  error
+	}{
+		"invalid schedule": {data: `"0 0 12 32 * *"`, err: fmt.Errorf("The range is not compliant for this part of Schedule. Expects numbers between %v-%v, got %v-%v from string %s", 1, 31, 32, 32, "32")},
+		"swapped bounds": {data: `{"expression":"0 0 6 * * *","notBefore":"2027-01-01T00:00:00Z","notAfter":"2026-01-01T00:00:00Z"}`,
+			err: fmt.Errorf("The bounds must be defined with NotBefore <= NotAfter, got %v - %v", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s Schedule
+			err := json.Unmarshal([]byte(test.data), &s)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Names of the checks performed by Lint
+const (
+	CheckImpossible = "impossible"
+	CheckLeapYear   = "leap-year"
+	CheckFrequency  = "frequency"
+	CheckDST        = "dst"
+	CheckRare       = "rare"
+)
+
+// frequencyFires caps the number of fire times inspected by the frequency check
+const frequencyFires = 10000
+
+// rareCycle is the number of years after which the calendar (mostly) repeats itself
+const rareCycle = 28
+
+// LintOptions configures the checks performed by Lint, zero values are replaced by defaults.
+type LintOptions struct {
+	// From is the start of the simulation, defaults to the current time.
+	From time.Time
+	// Window is the length of the simulation used by the frequency and DST checks, defaults to a year.
+	Window time.Duration
+	// Samples is the number of fire times reported as an evidence, defaults to 5.
+	Samples int
+	// MinInterval flags schedules firing more often, defaults to a minute.
+	MinInterval time.Duration
+	// RareDaysPerYear flags weekDay and monthDay combinations matching fewer days a year, defaults to 1.
+	RareDaysPerYear float64
+}
+
+// Finding describes a single problem found by Lint.
+type Finding struct {
+	Check    string
+	Message  string
+	Evidence []time.Time
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Check, f.Message)
+}
+
+func (o LintOptions) withDefaults() LintOptions {
+	if o.From.IsZero() {
+		o.From = time.Now()
+	}
+	if o.Window <= 0 {
+		o.Window = 366 * 24 * time.Hour
+	}
+	if o.Samples <= 0 {
+		o.Samples = 5
+	}
+	if o.MinInterval <= 0 {
+		o.MinInterval = time.Minute
+	}
+	if o.RareDaysPerYear <= 0 {
+		o.RareDaysPerYear = 1
+	}
+	return o
+}
+
+// Lint simulates the schedule and reports the definitions that are most likely a mistake:
+// schedules that never fire, fire only in leap years, fire too often, collide with DST changes
+// or combine weekDay and monthDay into a very rare event.
+func Lint(s Schedule, opts LintOptions) []Finding {
+	opts = opts.withDefaults()
+	findings := make([]Finding, 0)
+
+	samples, err := s.Times(opts.From, time.Time{}, opts.Samples)
+	if err != nil || len(samples) == 0 {
+		msg := "the schedule never fires"
+		if err != nil {
+			msg = fmt.Sprintf("the schedule never fires: %v", err)
+		}
+		return append(findings, Finding{Check: CheckImpossible, Message: msg})
+	}
+
+	if f, ok := lintLeapYear(s, samples); ok {
+		findings = append(findings, f)
+	}
+	if f, ok := lintFrequency(s, opts); ok {
+		findings = append(findings, f)
+	}
+	findings = append(findings, lintDST(s, opts)...)
+	if f, ok := lintRare(s, opts, samples); ok {
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// lintLeapYear checks whether the only day satisfying the schedule is 29th February
+func lintLeapYear(s Schedule, samples []time.Time) (Finding, bool) {
+	for m := 1; m <= 12; m++ {
+		if !s.Month.isin(m) {
+			continue
+		}
+		for d := 1; d <= 31; d++ {
+			if !s.MonthDay.isin(d) || (m == 2 && d == 29) {
+				continue
+			}
+			// year 2000 is a leap year, so the only invalid dates are the ones that never exist
+			if dt := time.Date(2000, time.Month(m), d, 0, 0, 0, 0, time.Local); dt.Day() == d {
+				return Finding{}, false
+			}
+		}
+	}
+	return Finding{
+		Check:    CheckLeapYear,
+		Message:  "the schedule fires only on 29th February, i.e. in leap years",
+		Evidence: samples,
+	}, true
+}
+
+// lintFrequency finds the shortest interval between two consecutive fire times within the window
+func lintFrequency(s Schedule, opts LintOptions) (Finding, bool) {
+	times, err := s.Times(opts.From, opts.From.Add(opts.Window), frequencyFires)
+	if err != nil || len(times) < 2 {
+		return Finding{}, false
+	}
+
+	first := -1
+	shortest := time.Duration(0)
+	for ix := 1; ix < len(times); ix++ {
+		gap := times[ix].Sub(times[ix-1])
+		if gap < opts.MinInterval && (first == -1 || gap < shortest) {
+			if first == -1 {
+				first = ix - 1
+			}
+			shortest = gap
+		}
+	}
+	if first == -1 {
+		return Finding{}, false
+	}
+
+	end := first + opts.Samples
+	if end > len(times) {
+		end = len(times)
+	}
+	return Finding{
+		Check:    CheckFrequency,
+		Message:  fmt.Sprintf("the schedule fires every %v, more often than the allowed %v", shortest, opts.MinInterval),
+		Evidence: times[first:end],
+	}, true
+}
+
+// lintDST inspects the days with a change of the UTC offset within the window.
+// The fire times falling into the skipped hour are moved, the ones in the repeated hour are ambiguous.
+func lintDST(s Schedule, opts LintOptions) []Finding {
+	findings := make([]Finding, 0)
+	gaps := make([]time.Time, 0)
+	repeats := make([]time.Time, 0)
+	// every hour fires once regardless of the repeated one, only specific hours are ambiguous
+	_, anyHour := s.Hour.(partAny)
+
+	start := time.Date(opts.From.Year(), opts.From.Month(), opts.From.Day(), 0, 0, 0, 0, time.Local)
+	for day := start; day.Before(opts.From.Add(opts.Window)); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		_, offStart := day.Zone()
+		_, offEnd := next.Zone()
+		if offStart == offEnd || !s.matchesDate(day) {
+			continue
+		}
+
+		times, err := s.Times(day, next.Add(-time.Second), 0)
+		if err != nil {
+			continue
+		}
+		for _, t := range times {
+			switch {
+			case !s.Matches(t):
+				gaps = append(gaps, t)
+			case !anyHour && (sameWallClock(t, t.Add(time.Hour)) || sameWallClock(t, t.Add(-time.Hour))):
+				repeats = append(repeats, t)
+			}
+		}
+	}
+
+	if len(gaps) > 0 {
+		findings = append(findings, Finding{
+			Check:    CheckDST,
+			Message:  "the schedule fires in the hour skipped by a DST change, the fire times are moved",
+			Evidence: limitTimes(gaps, opts.Samples),
+		})
+	}
+	if len(repeats) > 0 {
+		findings = append(findings, Finding{
+			Check:    CheckDST,
+			Message:  "the schedule fires in the hour repeated by a DST change, the fire times are ambiguous",
+			Evidence: limitTimes(repeats, opts.Samples),
+		})
+	}
+	return findings
+}
+
+// lintRare counts the days satisfying a schedule with both weekDay and monthDay specified
+func lintRare(s Schedule, opts LintOptions, samples []time.Time) (Finding, bool) {
+	_, anyWeekDay := s.WeekDay.(partAny)
+	_, anyMonthDay := s.MonthDay.(partAny)
+	if anyWeekDay || anyMonthDay {
+		return Finding{}, false
+	}
+
+	days := 0
+	start := time.Date(opts.From.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(rareCycle, 0, 0)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if s.matchesDate(day) {
+			days++
+		}
+	}
+
+	perYear := float64(days) / rareCycle
+	if perYear >= opts.RareDaysPerYear {
+		return Finding{}, false
+	}
+	return Finding{
+		Check: CheckRare,
+		Message: fmt.Sprintf("the combination of weekDay %s and monthDay %s matches %.2f days a year, less than %v",
+			s.WeekDay.getOrigin(), s.MonthDay.getOrigin(), perYear, opts.RareDaysPerYear),
+		Evidence: samples,
+	}, true
+}
+
+func sameWallClock(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay() &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
+
+func limitTimes(times []time.Time, limit int) []time.Time {
+	if len(times) > limit {
+		return times[:limit]
+	}
+	return times
+}
@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	from := time.Date(2019, time.Month(10), 7, 0, 0, 0, 0, time.Local)
+
+	tests := map[string]struct {
+		sch    string
+		opts   LintOptions
+		checks []string
+	}{
+		"clean daily":         {sch: "0 0 6 * * *", checks: []string{}},
+		"clean friday 13th":   {sch: "0 0 6 13 * 5", checks: []string{}},
+		"leap year":           {sch: "0 0 6 29 2 *", checks: []string{CheckLeapYear}},
+		"every second":        {sch: "* * * * * *", checks: []string{CheckFrequency}},
+		"allowed frequency":   {sch: "* * * * * *", opts: LintOptions{MinInterval: time.Second}, checks: []string{}},
+		"burst once a day":    {sch: "0,30 0 6 * * *", checks: []string{CheckFrequency}},
+		"rare weekday":        {sch: "0 0 6 13 2 5", checks: []string{CheckRare}},
+		"rare leap monday":    {sch: "0 0 6 29 2 1", checks: []string{CheckLeapYear, CheckRare}},
+		"lower rare treshold": {sch: "0 0 6 13 2 5", opts: LintOptions{RareDaysPerYear: 0.1}, checks: []string{}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sched, err := ParseSchedule(test.sch)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			test.opts.From = from
+
+			got := make([]string, 0)
+			for _, f := range Lint(sched, test.opts) {
+				got = append(got, f.Check)
+				if len(f.Evidence) == 0 {
+					t.Fatalf("Expected evidence for %v", f)
+				}
+			}
+			if !reflect.DeepEqual(test.checks, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.checks, got)
+			}
+		})
+	}
+}
+
+func TestLintImpossible(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	ended, _ := daily.Bounded(time.Time{}, time.Date(2019, time.Month(1), 1, 0, 0, 0, 0, time.Local))
+
+	got := Lint(ended, LintOptions{From: time.Date(2019, time.Month(10), 7, 0, 0, 0, 0, time.Local)})
+	if len(got) != 1 || got[0].Check != CheckImpossible {
+		t.Fatalf("Expected: %#v, got: %#v", CheckImpossible, got)
+	}
+}
+
+func TestLintDST(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+	local := time.Local
+	time.Local = prague
+	defer func() { time.Local = local }()
+
+	from := time.Date(2026, time.Month(1), 1, 0, 0, 0, 0, time.Local)
+
+	tests := map[string]struct {
+		sch  string
+		want []time.Time
+	}{
+		"skipped hour":   {sch: "0 30 2 * * *", want: []time.Time{time.Date(2026, time.Month(3), 29, 3, 30, 0, 0, time.Local)}},
+		"repeated hour":  {sch: "0 30 2 * * *", want: []time.Time{time.Date(2026, time.Month(10), 25, 2, 30, 0, 0, time.Local)}},
+		"safe hour":      {sch: "0 30 4 * * *"},
+		"hourly is safe": {sch: "0 0 * * * *"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sched, _ := ParseSchedule(test.sch)
+
+			found := false
+			for _, f := range lintDST(sched, LintOptions{From: from}.withDefaults()) {
+				for _, w := range test.want {
+					if f.Evidence[0].Equal(w) {
+						found = true
+					}
+				}
+				if len(test.want) == 0 {
+					t.Fatalf("Expected no finding, got: %v %v", f, f.Evidence)
+				}
+			}
+			if len(test.want) > 0 && !found {
+				t.Fatalf("Expected finding with evidence %v", test.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,37 @@ package schedule
 import (
 	"fmt"
 	"time"
-
-	"github.com/kubistmi/plango/utils"
 )
 
-// Next ...
+// Next finds the first time on or after `after` satisfying the schedule, the fraction of a second
+// of `after` is rounded up to the next whole second, i.e. the found time is never before `after`.
+// The search starts at NotBefore if `after` precedes it and ErrExhausted is returned
+// when the found time falls behind NotAfter. The empty Schedule is exhausted from the start.
 func (s Schedule) Next(after time.Time) (time.Time, error) {
+	if s.Second == nil {
+		return time.Date(0, 0, 0, 0, 0, 0, 0, time.Local), ErrExhausted
+	}
+	if !s.NotBefore.IsZero() && after.Before(s.NotBefore) {
+		after = s.NotBefore
+	}
+	// the schedule has the precision of seconds
+	if trunc := after.Truncate(time.Second); !trunc.Equal(after) {
+		after = trunc.Add(time.Second)
+	}
+
+	next, err := s.next(after)
+	if err != nil {
+		return next, err
+	}
+
+	if !s.NotAfter.IsZero() && next.After(s.NotAfter) {
+		return time.Date(0, 0, 0, 0, 0, 0, 0, time.Local), ErrExhausted
+	}
+	return next, nil
+}
+
+// next ignores the bounds of the schedule
+func (s Schedule) next(after time.Time) (time.Time, error) {
 
 	var reset time.Time
 	next := after
@@ -26,6 +51,100 @@ func (s Schedule) Next(after time.Time) (time.Time, error) {
 
 }
 
+// Times lists the times satisfying the schedule between `from` and `to` (both inclusive), at most `limit` of them.
+// Zero `to` leaves the end open and non-positive `limit` means no limit, at least one of them must be set.
+func (s Schedule) Times(from, to time.Time, limit int) ([]time.Time, error) {
+	if to.IsZero() && limit <= 0 {
+		return nil, fmt.Errorf("unable to list the times of the schedule, either the end or the limit must be set")
+	}
+
+	// the schedule has the precision of seconds, don't return anything before `from`
+	if trunc := from.Truncate(time.Second); !trunc.Equal(from) {
+		from = trunc.Add(time.Second)
+	}
+
+	times := make([]time.Time, 0)
+	for limit <= 0 || len(times) < limit {
+		next, err := s.Next(from)
+		if err == ErrExhausted {
+			break
+		}
+		if err != nil {
+			return times, err
+		}
+		if !to.IsZero() && next.After(to) {
+			break
+		}
+		// the wall clock may repeat during DST changes, keep the times strictly increasing
+		if len(times) == 0 || next.After(times[len(times)-1]) {
+			times = append(times, next)
+		}
+		if !next.Before(from) {
+			from = next.Add(time.Second)
+		} else {
+			from = from.Add(time.Second)
+		}
+	}
+	return times, nil
+}
+
+// maxLookBack limits the search of Prev, even the schedules firing on the 29th of February fire within 8 years
+const maxLookBack = 10 * 366 * 24 * time.Hour
+
+// Prev finds the last time before `before` satisfying the schedule, within its bounds.
+// The windows searched grow from a minute up to maxLookBack back in time, so the frequent schedules are cheap.
+func (s Schedule) Prev(before time.Time) (time.Time, error) {
+	to := before.Add(-time.Nanosecond)
+	if !s.NotAfter.IsZero() && to.After(s.NotAfter) {
+		to = s.NotAfter
+	}
+
+	for window := time.Minute; ; window *= 2 {
+		from := to.Add(-window)
+		last, found, err := s.last(from, to)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if found {
+			return last, nil
+		}
+		if window >= maxLookBack || (!s.NotBefore.IsZero() && from.Before(s.NotBefore)) {
+			return time.Time{}, fmt.Errorf("unable to find the time satisfying the schedule before %v", before)
+		}
+		to = from
+	}
+}
+
+// last finds the last time between `from` and `to` (both inclusive) satisfying the schedule.
+// It bisects the start of Next instead of listing the times, the schedules firing every second
+// of a rare month take a few dozen searches even in the longest windows.
+func (s Schedule) last(from, to time.Time) (time.Time, bool, error) {
+	first, err := s.Next(from)
+	if err == ErrExhausted || (err == nil && first.After(to)) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// lo is the last time found so far, no time after it satisfies the schedule on or after hi
+	lo, hi := first, to.Truncate(time.Second).Add(time.Second)
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+		next, err := s.Next(mid)
+		if err != nil && err != ErrExhausted {
+			return time.Time{}, false, err
+		}
+		// the wall clock may repeat during DST changes, move only forward
+		if err == nil && !next.After(to) && next.After(lo) {
+			lo = next
+		} else {
+			hi = mid
+		}
+	}
+	return lo, true, nil
+}
+
 // NextTime ...
 func (s Schedule) NextTime(next time.Time) (time.Time, time.Time) {
 
@@ -44,86 +163,70 @@ func (s Schedule) NextTime(next time.Time) (time.Time, time.Time) {
 
 // NextDate ...
 func (s Schedule) NextDate(next time.Time) (time.Time, error) {
+	// the search jumps to the next candidate month, monthDay or weekDay in every iteration,
+	// so even Monday 29th February (once in 28 years) is found within the limit
 	// TODO: should this be a config variable?
-	iter := 50
-
-	switch s.WeekDay.(type) {
+	iter := 1000
+
+	for i := 0; i < iter; i++ {
+		// first, check the month
+		// if it doesn't fit, jump to the first day of the next satisfying month
+		nxtMonth, mShift := s.Month.compareTime(int(next.Month()))
+		if mShift != 0 || nxtMonth != int(next.Month()) {
+			next = time.Date(next.Year()+mShift, time.Month(nxtMonth), 1, 0, 0, 0, 0, time.Local)
+			continue
+		}
 
-	case partAny:
-		// the easy part, for non-specific weekDay, just go through the calendar
-		var nxtMday, nxtMonth int
-		var mdShift, mShift int
-
-		for i := 0; i < iter; i++ {
-			nxtMday, mdShift = s.MonthDay.compareTime(next.Day())
-			next = next.AddDate(0, mdShift, 0)
-
-			nxtMonth, mShift = s.Month.compareTime(int(next.Month()))
-			next = next.AddDate(mShift, nxtMonth-int(next.Month()), nxtMday-next.Day())
-
-			if int(next.Month()) != nxtMonth {
-				next = next.AddDate(0, 0, 1)
-				continue
-			} else {
-				return next, nil
+		// then, check the monthDay
+		// if it doesn't fit, jump to the next satisfying day of this month or to the next month
+		nxtMday, mdShift := s.MonthDay.compareTime(next.Day())
+		if nxtMday != next.Day() {
+			jump := time.Date(next.Year(), next.Month(), nxtMday, 0, 0, 0, 0, time.Local)
+			// e.g. the 31st in a month with 30 days
+			if mdShift != 0 || jump.Month() != next.Month() {
+				jump = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, time.Local)
 			}
+			next = jump
+			continue
 		}
 
-	default:
-
-		var wdMday, wdNext, wdShift int
-
-		for i := 0; i < iter; i++ {
-			// first, check whether the weekday is OK
-			// shift by:
-			//    - difference in days
-			//    - difference in weekdays * 7
-			wdNext, wdShift = s.WeekDay.compareTime(int(next.Weekday()))
-			next = next.AddDate(0, 0, wdNext-int(next.Weekday())+wdShift*7)
-
-			// then, check the monthDay and month
-			wdMday, wdShift = s.MonthDay.compareTime(next.Day())
-			wdMonth, wdMshift := s.Month.compareTime(int(next.Month()))
-
-			// very greedy early exit, if the monthDay and month are OK, return
-			if wdShift == 0 && wdMday == next.Day() && wdMonth == int(next.Month()) {
-				if s.MonthDay.isin(next.Day()) && s.Month.isin(int(next.Month())) {
-					return next, nil
-				}
-				next = next.AddDate(0, 0, 1)
-				continue
-			} else if wdMshift == 1 {
-				// if Schedule.Month too high, then jump to the next year and ...
-				// else to the max(Schedule.Month, 1) and ...
-				// ... lowest of the Schedule.MonthDay
-				next = next.AddDate(1, s.Month.min(1)-int(next.Month()), s.MonthDay.min(1)-next.Day())
-			} else {
-				monthShift := []int{wdMonth - int(next.Month()), wdShift}
-				next = next.AddDate(0, utils.FindMax(monthShift), s.MonthDay.min(1)-next.Day())
-			}
+		// finally, check the weekDay
+		// if it doesn't fit, shift by:
+		//    - difference in weekdays
+		//    - whole week if the weekday is lower
+		nxtWday, wdShift := s.WeekDay.compareTime(int(next.Weekday()))
+		if wdShift != 0 || nxtWday != int(next.Weekday()) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+nxtWday-int(next.Weekday())+wdShift*7, 0, 0, 0, 0, time.Local)
+			continue
 		}
+
+		return next, nil
 	}
 	//! if the date cannot be found, not sure this is reachable
 	return time.Date(0, 0, 0, 0, 0, 0, 0, time.Local), fmt.Errorf("unable to find the date satisfying the schedule")
 }
 
+// findCandidates returns the lowest value of the part followed by (at most) two lowest values not lower than `next`.
+// These are the only values the next time can consist of:
+//   - the current value if the lower parts can still be satisfied,
+//   - the following value if they can't,
+//   - the lowest value if the higher part shifts.
 func findCandidates(p part, next int, check int) []int {
-	candidates := make([]int, 0, 60)
+	candidates := make([]int, 0, 3)
 	switch v := p.(type) {
 	case partList:
+		candidates = append(candidates, v.min(0))
 		for _, val := range v.List {
-			if val >= next {
+			if val >= next && val != candidates[0] && len(candidates) < 3 {
 				candidates = append(candidates, val)
 			}
 		}
-		if len(candidates) >= 3 {
-			candidates = candidates[:3]
-		} else {
-			candidates = append([]int{v.min(0)}, candidates...)
-		}
 	case partAny:
-		for i := 0; i < 4; i++ {
-			candidates = append(candidates, (next+i)%check)
+		candidates = append(candidates, 0)
+		for i := 0; i < 2; i++ {
+			if val := next + i; val != 0 && val < check {
+				candidates = append(candidates, val)
+			}
 		}
 	}
 	return candidates
@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// referenceHorizon limits the search of the reference implementation
+var referenceHorizon = 30 * 366 * 24 * time.Hour
+
+// referenceNext is the brute-force counterpart of Schedule.Next. It steps second by second
+// and skips the days, hours and minutes not satisfying the schedule as a whole.
+func referenceNext(s Schedule, after time.Time) (time.Time, bool) {
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), after.Second(), 0, time.Local)
+	// the schedule has the precision of seconds, the fraction of a second is past the whole one
+	if t.Before(after) {
+		t = t.Add(time.Second)
+	}
+	end := t.Add(referenceHorizon)
+
+	for t.Before(end) {
+		if !s.matchesDate(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.Local)
+			continue
+		}
+		if !s.Hour.isin(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.Local)
+			continue
+		}
+		if !s.Minute.isin(t.Minute()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, time.Local)
+			continue
+		}
+		if s.Matches(t) {
+			return t, true
+		}
+		t = t.Add(time.Second)
+	}
+	return time.Time{}, false
+}
+
+// randomPart generates a random definition of a single part of the schedule
+func randomPart(rnd *rand.Rand, lim [2]int) string {
+	switch rnd.Intn(4) {
+	case 0:
+		return "*"
+	case 1:
+		a, b := lim[0]+rnd.Intn(lim[1]-lim[0]+1), lim[0]+rnd.Intn(lim[1]-lim[0]+1)
+		if a == b {
+			return strconv.Itoa(a)
+		}
+		return strconv.Itoa(a) + "-" + strconv.Itoa(b)
+	case 2:
+		n := 2 + rnd.Intn(4)
+		vals := make([]string, n)
+		for ix := range vals {
+			vals[ix] = strconv.Itoa(lim[0] + rnd.Intn(lim[1]-lim[0]+1))
+		}
+		return strings.Join(vals, ",")
+	default:
+		return strconv.Itoa(lim[0] + rnd.Intn(lim[1]-lim[0]+1))
+	}
+}
+
+// randomSchedule generates a random valid schedule
+func randomSchedule(rnd *rand.Rand) Schedule {
+	for {
+		parts := make([]string, len(partOrder))
+		for ix, p := range partOrder {
+			parts[ix] = randomPart(rnd, partLimits[p])
+		}
+		if sched, err := ParseSchedule(strings.Join(parts, " ")); err == nil {
+			return sched
+		}
+	}
+}
+
+// randomTime generates a random time between years 2000 and 2040
+func randomTime(rnd *rand.Rand) time.Time {
+	return time.Date(2000+rnd.Intn(40), time.Month(1+rnd.Intn(12)), 1+rnd.Intn(31),
+		rnd.Intn(24), rnd.Intn(60), rnd.Intn(60), rnd.Intn(2)*rnd.Intn(1e9), time.Local)
+}
+
+// checkNext asserts the properties of Schedule.Next against the reference implementation:
+//   - the found time is not before `after` (Next is inclusive, the fractions of a second are rounded up)
+//   - the found time satisfies the schedule
+//   - there is no time satisfying the schedule between `after` and the found time
+func checkNext(t *testing.T, sched Schedule, after time.Time) {
+	t.Helper()
+
+	want, found := referenceNext(sched, after)
+	got, err := sched.Next(after)
+	if !found {
+		if err == nil && got.Before(after.Add(referenceHorizon)) {
+			t.Fatalf("%s after %v: expected no time within the horizon, got: %v", sched, after, got)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("%s after %v: expected %v, got error: %v", sched, after, want, err)
+	}
+	if got.Before(after) {
+		t.Fatalf("%s after %v: found time %v is in the past", sched, after, got)
+	}
+	if !sched.Matches(got) {
+		t.Fatalf("%s after %v: found time %v doesn't satisfy the schedule", sched, after, got)
+	}
+	if !got.Equal(want) {
+		t.Fatalf("%s after %v: skipped %v, got: %v", sched, after, want, got)
+	}
+}
+
+// inUTC runs the calendar properties without DST, the wall clock doesn't exist or repeats otherwise
+func inUTC() func() {
+	local := time.Local
+	time.Local = time.UTC
+	return func() { time.Local = local }
+}
+
+func TestNextProperties(t *testing.T) {
+	defer inUTC()()
+
+	iterations := 5000
+	if testing.Short() {
+		iterations = 500
+	}
+
+	rnd := rand.New(rand.NewSource(20191007))
+	for i := 0; i < iterations; i++ {
+		checkNext(t, randomSchedule(rnd), randomTime(rnd))
+	}
+}
+
+func TestNextConsecutive(t *testing.T) {
+	defer inUTC()()
+
+	rnd := rand.New(rand.NewSource(20191012))
+	for i := 0; i < 200; i++ {
+		sched := randomSchedule(rnd)
+		after := randomTime(rnd)
+
+		// walk a few consecutive fire times, each of them must be found by the reference too
+		for j := 0; j < 10; j++ {
+			checkNext(t, sched, after)
+			next, err := sched.Next(after)
+			if err != nil {
+				break
+			}
+			after = next.Add(time.Second)
+		}
+	}
+}
+
+func FuzzParseSchedule(f *testing.F) {
+	for _, seed := range []string{"* * * * * *", "0 2-5 * * * 0", "0 30 12 5 1,2 *", "55-58 23-29 3-6 24-29 1-3 5-2", "0 0 12 32 * *", "a b c e * d"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, def string) {
+		sched, err := ParseSchedule(def)
+		if err != nil {
+			return
+		}
+
+		// a parsed schedule must survive the round trip through its definition
+		again, err := ParseSchedule(sched.String())
+		if err != nil {
+			t.Fatalf("%q: unable to parse the definition %q: %v", def, sched.String(), err)
+		}
+		if again.String() != sched.String() {
+			t.Fatalf("%q: expected %q, got: %q", def, sched.String(), again.String())
+		}
+	})
+}
+
+func FuzzNext(f *testing.F) {
+	f.Add("0 0 6 * * *", int64(1570406400))
+	f.Add("5 33 15 31 10,12 *", int64(1575133200))
+	f.Add("1 1 1 10 * 4", int64(1570410061))
+	f.Add("0 0 0 29 2 *", int64(1477849527))
+
+	defer inUTC()()
+
+	f.Fuzz(func(t *testing.T, def string, unix int64) {
+		sched, err := ParseSchedule(def)
+		if err != nil {
+			return
+		}
+		// keep the time within the years 1970 - 2100
+		after := time.Unix(unix%(130*365*24*3600), 0)
+		if after.Before(time.Unix(0, 0)) {
+			after = after.Add(130 * 365 * 24 * time.Hour)
+		}
+		checkNext(t, sched, after)
+	})
+}
@@ -34,7 +34,12 @@ type part interface {
 }
 
 // Schedule ...
-type Schedule struct{ Second, Minute, Hour, MonthDay, Month, WeekDay part }
+type Schedule struct {
+	Second, Minute, Hour, MonthDay, Month, WeekDay part
+	// NotBefore and NotAfter limit the active window of the Schedule, zero value means unbounded.
+	// See Bounded.
+	NotBefore, NotAfter time.Time
+}
 
 // checkSchedule
 func checkSchedule(sch Schedule) error {
@@ -67,6 +72,31 @@ func checkSchedule(sch Schedule) error {
 	return nil
 }
 
+// String returns the definition of the schedule as accepted by ParseSchedule.
+func (s Schedule) String() string {
+	if s.Second == nil {
+		return ""
+	}
+
+	parts := []part{s.Second, s.Minute, s.Hour, s.MonthDay, s.Month, s.WeekDay}
+	texts := make([]string, len(parts))
+	for ix, p := range parts {
+		texts[ix] = p.getOrigin()
+	}
+	return strings.Join(texts, " ")
+}
+
+// Matches checks whether the time (with the precision of seconds) satisfies the schedule and its bounds.
+func (s Schedule) Matches(t time.Time) bool {
+	return s.Within(t) && s.matchesDate(t) &&
+		s.Second.isin(t.Second()) && s.Minute.isin(t.Minute()) && s.Hour.isin(t.Hour())
+}
+
+// matchesDate checks only the date parts of the schedule
+func (s Schedule) matchesDate(t time.Time) bool {
+	return s.MonthDay.isin(t.Day()) && s.Month.isin(int(t.Month())) && s.WeekDay.isin(int(t.Weekday()))
+}
+
 // PartAny defines schedule based on the string "*". This definition will trigger on every occurence it can.
 // E.g. using * in monthDays field means the job will be run every day of the month (with regard to other definitions, such as weekDay).
 type partAny struct {
@@ -196,6 +226,12 @@ func ParseSchedule(schedule string) (Schedule, error) {
 				}
 			}
 
+			// check the limits before building the list, huge ranges would exhaust the memory
+			err = partList{Text: p, List: limsI}.checkPart(partLim)
+			if err != nil {
+				return Schedule{}, err
+			}
+
 			min := utils.FindMin(limsI)
 			max := utils.FindMax(limsI)
 
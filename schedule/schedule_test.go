@@ -175,6 +175,7 @@ func TestParseSchedule(t *testing.T) {
 		"error list and range":           {sch: "0 11-15,16 0 0 * 0", want: Schedule{}, err: fmt.Errorf("Unable to convert %s to an integer", "15,16")},
 		"error range and list":           {sch: "0 9,17-20 0 0 * 0", want: Schedule{}, err: fmt.Errorf("Unable to convert %s to an integer", "9,17")},
 		"only intervals":                 {sch: "55-58 23-29 3-6 24-29 1-3 5-2", want: intervals, err: nil},
+		"error huge range":               {sch: "0-100000000000 0 0 * * *", want: Schedule{}, err: fmt.Errorf("The range is not compliant for this part of Schedule. Expects numbers between %v-%v, got %v-%v from string %s", 0, 59, 0, 100000000000, "0-100000000000")},
 	}
 
 	for name, test := range tests {
@@ -305,6 +306,7 @@ func TestNext(t *testing.T) {
 		err   error
 	}{
 		"this second":                    {sched: everySecond, after: time.Date(2019, time.Month(10), 7, 23, 20, 0, 0, time.Local), want: time.Date(2019, time.Month(10), 7, 23, 20, 0, 0, time.Local)},
+		"fraction of a second":           {sched: everySecond, after: time.Date(2019, time.Month(10), 7, 23, 20, 0, 500, time.Local), want: time.Date(2019, time.Month(10), 7, 23, 20, 1, 0, time.Local)},
 		"next year":                      {sched: specificMDHMS, after: time.Date(2019, time.Month(10), 7, 23, 20, 0, 0, time.Local), want: time.Date(2020, time.Month(1), 5, 12, 30, 0, 0, time.Local)},
 		"in 10 minutes":                  {sched: listHMS, after: time.Date(2019, time.Month(3), 25, 16, 35, 0, 0, time.Local), want: time.Date(2019, time.Month(3), 25, 16, 46, 55, 0, time.Local)},
 		"in an hour":                     {sched: listHMS, after: time.Date(2019, time.Month(3), 25, 16, 51, 0, 0, time.Local), want: time.Date(2019, time.Month(3), 25, 17, 46, 55, 0, time.Local)},
@@ -332,3 +334,131 @@ func TestNext(t *testing.T) {
 	}
 
 }
+
+func TestString(t *testing.T) {
+	tests := map[string]struct {
+		sch  string
+		want string
+	}{
+		"every second": {sch: "* * * * * *", want: "* * * * * *"},
+		"mixed":        {sch: "0 2-5 3,1 * * 0", want: "0 2-5 3,1 * * 0"},
+		"only ranges":  {sch: "55-58 23-29 3-6 24-29 1-3 5-2", want: "55-58 23-29 3-6 24-29 1-3 5-2"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sched, _ := ParseSchedule(test.sch)
+			got := sched.String()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+	if got := (Schedule{}).String(); got != "" {
+		t.Fatalf("Expected empty string for zero Schedule, got: %#v", got)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	workdays, _ := ParseSchedule("0 30 8 * * 1-5")
+	bounded, _ := workdays.Bounded(time.Time{}, time.Date(2019, time.Month(10), 10, 0, 0, 0, 0, time.Local))
+
+	tests := map[string]struct {
+		sched Schedule
+		t     time.Time
+		want  bool
+	}{
+		"matching monday":      {sched: workdays, t: time.Date(2019, time.Month(10), 7, 8, 30, 0, 0, time.Local), want: true},
+		"ignores nanoseconds":  {sched: workdays, t: time.Date(2019, time.Month(10), 7, 8, 30, 0, 500, time.Local), want: true},
+		"wrong second":         {sched: workdays, t: time.Date(2019, time.Month(10), 7, 8, 30, 1, 0, time.Local), want: false},
+		"wrong hour":           {sched: workdays, t: time.Date(2019, time.Month(10), 7, 9, 30, 0, 0, time.Local), want: false},
+		"sunday":               {sched: workdays, t: time.Date(2019, time.Month(10), 6, 8, 30, 0, 0, time.Local), want: false},
+		"inside the bounds":    {sched: bounded, t: time.Date(2019, time.Month(10), 9, 8, 30, 0, 0, time.Local), want: true},
+		"outside of the bound": {sched: bounded, t: time.Date(2019, time.Month(10), 10, 8, 30, 0, 0, time.Local), want: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.sched.Matches(test.t)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestTimes(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	bounded, _ := daily.Bounded(time.Time{}, time.Date(2019, time.Month(10), 9, 12, 0, 0, 0, time.Local))
+	everySecond, _ := ParseSchedule("* * * * * *")
+
+	day := func(d, h, m, s int) time.Time { return time.Date(2019, time.Month(10), d, h, m, s, 0, time.Local) }
+
+	tests := map[string]struct {
+		sched Schedule
+		from  time.Time
+		to    time.Time
+		limit int
+		want  []time.Time
+		err   error
+	}{
+		"window":              {sched: daily, from: day(7, 0, 0, 0), to: day(9, 6, 0, 0), want: []time.Time{day(7, 6, 0, 0), day(8, 6, 0, 0), day(9, 6, 0, 0)}},
+		"limit":               {sched: daily, from: day(7, 7, 0, 0), limit: 2, want: []time.Time{day(8, 6, 0, 0), day(9, 6, 0, 0)}},
+		"window and limit":    {sched: daily, from: day(7, 0, 0, 0), to: day(8, 12, 0, 0), limit: 5, want: []time.Time{day(7, 6, 0, 0), day(8, 6, 0, 0)}},
+		"exhausted":           {sched: bounded, from: day(7, 0, 0, 0), limit: 5, want: []time.Time{day(7, 6, 0, 0), day(8, 6, 0, 0), day(9, 6, 0, 0)}},
+		"empty schedule":      {sched: Schedule{}, from: day(7, 7, 0, 0), limit: 2, want: []time.Time{}},
+		"empty window":        {sched: daily, from: day(7, 7, 0, 0), to: day(8, 5, 0, 0), want: []time.Time{}},
+		"skip partial second": {sched: everySecond, from: day(7, 7, 0, 0).Add(time.Millisecond), limit: 2, want: []time.Time{day(7, 7, 0, 1), day(7, 7, 0, 2)}},
+		"error unbounded":     {sched: daily, from: day(7, 7, 0, 0), err: fmt.Errorf("unable to list the times of the schedule, either the end or the limit must be set")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.sched.Times(test.from, test.to, test.limit)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+			if err == nil && !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %v, got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPrev(t *testing.T) {
+	daily, _ := ParseSchedule("0 0 6 * * *")
+	leap, _ := ParseSchedule("0 0 0 29 2 *")
+	everySecond, _ := ParseSchedule("* * * * * *")
+	february, _ := ParseSchedule("* * * * 2 *")
+	bounded, _ := daily.Bounded(time.Date(2019, time.Month(10), 5, 0, 0, 0, 0, time.Local), time.Date(2019, time.Month(10), 8, 0, 0, 0, 0, time.Local))
+
+	day := func(d, h, m, s int) time.Time { return time.Date(2019, time.Month(10), d, h, m, s, 0, time.Local) }
+
+	tests := map[string]struct {
+		sched  Schedule
+		before time.Time
+		want   time.Time
+		err    error
+	}{
+		"daily":          {sched: daily, before: day(9, 12, 0, 0), want: day(9, 6, 0, 0)},
+		"exclusive":      {sched: daily, before: day(9, 6, 0, 0), want: day(8, 6, 0, 0)},
+		"every second":   {sched: everySecond, before: day(9, 6, 0, 0).Add(time.Millisecond), want: day(9, 6, 0, 0)},
+		"rare month":     {sched: february, before: day(9, 6, 0, 0), want: time.Date(2019, time.Month(2), 28, 23, 59, 59, 0, time.Local)},
+		"leap day":       {sched: leap, before: day(9, 6, 0, 0), want: time.Date(2016, time.Month(2), 29, 0, 0, 0, 0, time.Local)},
+		"not after":      {sched: bounded, before: day(9, 12, 0, 0), want: day(7, 6, 0, 0)},
+		"not before":     {sched: bounded, before: day(5, 6, 0, 0), err: fmt.Errorf("unable to find the time satisfying the schedule before %v", day(5, 6, 0, 0))},
+		"within a bound": {sched: bounded, before: day(6, 0, 0, 0), want: day(5, 6, 0, 0)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.sched.Prev(test.before)
+			if !reflect.DeepEqual(test.err, err) {
+				t.Fatalf("Expected: %#v, got: %#v", test.err, err)
+			}
+			if err == nil && !got.Equal(test.want) {
+				t.Fatalf("Expected: %v, got: %v", test.want, got)
+			}
+		})
+	}
+}
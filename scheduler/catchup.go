@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/schedule"
+	"github.com/kubistmi/plango/store"
+)
+
+// missedBatch is the number of the fire times listed at once while walking the missed ones
+const missedBatch = 1000
+
+// CatchUp dispatches the fires of the active jobs missed since their last recorded scheduled run,
+// according to the Misfire policies of the jobs. It's meant to be called once the jobs are loaded on start,
+// the jobs that have never run have nothing to catch up.
+func (s *Scheduler) CatchUp(runs store.RunStore) error {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	jobs := make([]job.Job, 0, len(s.entries))
+	for _, e := range s.entries {
+		if e.job.Active && e.job.Misfire.Keep() > 0 {
+			jobs = append(jobs, e.job)
+		}
+	}
+	s.mu.Unlock()
+
+	fired := make([]firing, 0)
+	for _, j := range jobs {
+		last, err := lastScheduled(runs, j.ID, now)
+		if err != nil {
+			return err
+		}
+		if last.IsZero() {
+			continue
+		}
+
+		// the fire at the very moment is planned by the Scheduler already
+		since := j.Misfire.Since(last, now)
+		times, err := missed(j.Schedule, since, now.Add(-time.Nanosecond), j.Misfire.Keep())
+		if err != nil {
+			// the unsatisfiable schedule isn't planned either
+			continue
+		}
+		for _, t := range times {
+			if j.Paused(t) {
+				continue
+			}
+			run := job.Run{ID: store.NewID(), JobID: j.ID, ScheduledTime: t, Trigger: job.TriggerSchedule, Attempt: 1}
+			run.Transition(job.StatusPlanned, now)
+			fired = append(fired, firing{job: j, run: run})
+		}
+	}
+
+	for _, f := range fired {
+		s.dispatch.Dispatch(f.job, f.run)
+	}
+	return nil
+}
+
+// lastScheduled finds the latest ScheduledTime of the scheduled runs of the Job, zero if it has none.
+// The runs are listed by their StartTime, the ones that never started (e.g. queued) come last,
+// so all of them are walked. The planned runs and the ones planned ahead of now haven't fired yet.
+func lastScheduled(runs store.RunStore, jobID string, now time.Time) (time.Time, error) {
+	var last time.Time
+	filter := store.RunFilter{JobID: jobID, Trigger: []job.Trigger{job.TriggerSchedule}, Limit: missedBatch}
+	for {
+		page, err := runs.ListRuns(filter)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, run := range page.Runs {
+			if run.Status == job.StatusPlanned || run.ScheduledTime.After(now) {
+				continue
+			}
+			if run.ScheduledTime.After(last) {
+				last = run.ScheduledTime
+			}
+		}
+		if page.Next == "" {
+			return last, nil
+		}
+		filter.Cursor = page.Next
+	}
+}
+
+// missed lists the fire times of the schedule within from-to, only the latest `keep` of them
+func missed(sch schedule.Schedule, from, to time.Time, keep int) ([]time.Time, error) {
+	res := make([]time.Time, 0, keep)
+	for !from.After(to) {
+		times, err := sch.Times(from, to, missedBatch)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, times...)
+		if len(res) > keep {
+			res = append(res[:0], res[len(res)-keep:]...)
+		}
+		if len(times) < missedBatch {
+			break
+		}
+		from = times[len(times)-1].Add(time.Second)
+	}
+	return res, nil
+}
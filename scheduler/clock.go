@@ -0,0 +1,40 @@
+package scheduler
+
+import "time"
+
+// Clock provides the current time and the timers, the tests replace it with a fake one
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer delivers the time on its channel once it expires, see time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// RealClock is the Clock backed by the package time
+type RealClock struct{}
+
+// Now returns the current local time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer starts a timer expiring after the duration
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
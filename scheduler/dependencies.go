@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/store"
+)
+
+// dependencyRetention is how long the outcomes of the finished runs are kept to trigger the dependent jobs
+const dependencyRetention = 48 * time.Hour
+
+// outcome identifies the Runs of the Job sharing the logical time
+type outcome struct {
+	jobID string
+	at    int64
+}
+
+// result is the final status of the Run together with the time it was recorded
+type result struct {
+	status job.Status
+	at     time.Time
+}
+
+// Finished records the final Run of the Job, i.e. the last of its attempts, and dispatches the Runs of the active (not paused) jobs
+// depending on it whose Condition is satisfied. The dispatched Runs share the logical time (ScheduledTime) of the Run
+// and every dependent job is triggered at most once for the logical time.
+func (s *Scheduler) Finished(j job.Job, run job.Run) {
+	now := s.clock.Now()
+	at := run.ScheduledTime.UnixNano()
+
+	s.mu.Lock()
+	s.prune(now)
+	s.outcomes[outcome{jobID: j.ID, at: at}] = result{status: run.Status, at: now}
+
+	fired := make([]firing, 0)
+	for _, e := range s.entries {
+		deps := e.job.DependsOn
+		if !e.job.Active || e.job.Paused(now) || deps == nil || !dependsOn(deps, j.ID) {
+			continue
+		}
+		key := outcome{jobID: e.job.ID, at: at}
+		if _, ok := s.triggered[key]; ok {
+			continue
+		}
+
+		finished := make(map[string]job.Status, len(deps.Jobs))
+		for _, upstream := range deps.Jobs {
+			if res, ok := s.outcomes[outcome{jobID: upstream, at: at}]; ok {
+				finished[upstream] = res.status
+			}
+		}
+		if !deps.Satisfied(finished) {
+			continue
+		}
+
+		s.triggered[key] = now
+		triggered := job.Run{ID: store.NewID(), JobID: e.job.ID, ScheduledTime: run.ScheduledTime, Trigger: job.TriggerDependency, Attempt: 1}
+		fired = append(fired, firing{job: e.job, run: triggered})
+	}
+	s.mu.Unlock()
+
+	for _, f := range fired {
+		s.dispatch.Dispatch(f.job, f.run)
+	}
+}
+
+// prune forgets the outcomes and the triggers older than dependencyRetention
+func (s *Scheduler) prune(now time.Time) {
+	for key, res := range s.outcomes {
+		if now.Sub(res.at) > dependencyRetention {
+			delete(s.outcomes, key)
+		}
+	}
+	for key, at := range s.triggered {
+		if now.Sub(at) > dependencyRetention {
+			delete(s.triggered, key)
+		}
+	}
+}
+
+func dependsOn(deps *job.Dependencies, id string) bool {
+	for _, upstream := range deps.Jobs {
+		if upstream == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,476 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kubistmi/plango/executor"
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/store"
+)
+
+// recoverBatch is the number of the Runs listed at once by Recover
+const recoverBatch = 100
+
+// Runner is the Dispatcher executing every fired Job in its own goroutine,
+// the Runs are saved once they start and once more when they finish.
+// The Concurrency of the Job limits its Runs running at the same time, the retries of a Run keep its slot.
+type Runner struct {
+	ctx      context.Context
+	executor executor.Executor
+	runs     store.RunStore
+	wg       sync.WaitGroup
+
+	mu sync.Mutex
+	// stopped refuses the new Runs, see Stop; stopping is closed then
+	stopped  bool
+	stopping chan struct{}
+	// slots are the running and queued Runs by the ID of their Job
+	slots map[string]*slots
+	// handles of the Runs running or waiting for their retry by their IDs
+	handles map[string]*handle
+
+	// Done receives the result of every finished run, defaults to logging it
+	Done func(res executor.Result, err error)
+	// Finished receives the last attempt of every Run, if set
+	Finished func(j job.Job, run job.Run)
+	// Logs keeps the output of the runs, if set
+	Logs logs.Store
+	// random randomizes the delays of the retries
+	random func() float64
+}
+
+// slots of a single Job
+type slots struct {
+	// running are ordered from the oldest
+	running []*running
+	queued  []queued
+}
+
+type running struct {
+	runID  string
+	cancel context.CancelFunc
+}
+
+type queued struct {
+	job job.Job
+	run job.Run
+}
+
+// NewRunner prepares the Runner, cancelling the context kills the running commands
+func NewRunner(ctx context.Context, exec executor.Executor, runs store.RunStore) *Runner {
+	return &Runner{
+		ctx:      ctx,
+		executor: exec,
+		runs:     runs,
+		stopping: make(chan struct{}),
+		slots:    make(map[string]*slots),
+		handles:  make(map[string]*handle),
+		random:   rand.Float64,
+		Done: func(res executor.Result, err error) {
+			if err != nil {
+				log.Printf("Run %s of job %s: %v", res.Run.ID, res.Run.JobID, err)
+				return
+			}
+			log.Printf("Run %s of job %s %s with exit code %v", res.Run.ID, res.Run.JobID, res.Run.Status, res.Run.ExitCode)
+		},
+	}
+}
+
+// Dispatch starts the Run of the Job unless the Concurrency of the Job says otherwise
+func (r *Runner) Dispatch(j job.Job, run job.Run) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		r.skip(run)
+		return
+	}
+
+	s, ok := r.slots[j.ID]
+	if !ok {
+		s = &slots{}
+		r.slots[j.ID] = s
+	}
+
+	if limit := j.Config.Concurrency.Limit(); limit > 0 && len(s.running) >= limit {
+		switch j.Config.Concurrency.Policy {
+		case job.ConcurrencyForbid:
+			r.skip(run)
+			return
+		case job.ConcurrencyReplace:
+			oldest := s.running[0]
+			s.running = s.running[1:]
+			oldest.cancel()
+		default:
+			run.Transition(job.StatusQueued, time.Now())
+			r.save(run)
+			s.queued = append(s.queued, queued{job: j, run: run})
+			return
+		}
+	}
+	r.start(s, j, run)
+}
+
+// Recover finishes the Runs left queued or running by the previous instance of plango (e.g. stopped by a crash),
+// nothing would pick them up otherwise. The running ones failed as their commands are gone, the queued ones are cancelled.
+// It's meant to be called on start before any Run is dispatched.
+func (r *Runner) Recover() error {
+	now := time.Now()
+	filter := store.RunFilter{Status: []job.Status{job.StatusQueued, job.StatusRunning}, Limit: recoverBatch}
+	for {
+		// the finished Runs leave the filter, the first page is listed again until it's empty
+		page, err := r.runs.ListRuns(filter)
+		if err != nil {
+			return err
+		}
+		if len(page.Runs) == 0 {
+			return nil
+		}
+		for _, run := range page.Runs {
+			to := job.StatusCancelled
+			if run.Status == job.StatusRunning {
+				to, run.ExitCode, run.EndTime = job.StatusFailed, -1, now
+			}
+			if err := run.Transition(to, now); err != nil {
+				return err
+			}
+			if err := r.runs.SaveRun(run); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skip records the Run that never runs
+func (r *Runner) skip(run job.Run) {
+	now := time.Now()
+	run.StartTime, run.EndTime = now, now
+	if err := run.Transition(job.StatusSkipped, now); err != nil {
+		log.Printf("Run %s of job %s: %v", run.ID, run.JobID, err)
+		return
+	}
+	r.save(run)
+}
+
+// Skipped records the Run skipped by the Scheduler, i.e. the fire of the paused Job, see Scheduler.Skipped
+func (r *Runner) Skipped(j job.Job, run job.Run) {
+	now := time.Now()
+	run.StartTime, run.EndTime = now, now
+	r.save(run)
+}
+
+// start runs the Run in a new slot, the failed Run is retried according to the RetryPolicy of the Job
+func (r *Runner) start(s *slots, j job.Job, run job.Run) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	slot := &running{runID: run.ID, cancel: cancel}
+	s.running = append(s.running, slot)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.release(j.ID, slot)
+		defer cancel()
+		r.attempts(ctx, j, run)
+	}()
+}
+
+// attempts executes the Run and its retries until one of them succeeds or the RetryPolicy gives up,
+// the last attempt is passed to Finished unless it was killed by the cancelled context of the Runner
+func (r *Runner) attempts(ctx context.Context, j job.Job, run job.Run) {
+	for {
+		run = r.execute(ctx, j, run).Run
+		if j.Config.Retry.Retryable(run) {
+			if run = r.retry(ctx, j, run); run.Status == job.StatusQueued {
+				continue
+			}
+		}
+		if r.Finished != nil && r.ctx.Err() == nil {
+			r.Finished(j, run)
+		}
+		return
+	}
+}
+
+// Backfill executes the queued Runs of the Job in their order, at most `parallelism` of them at the same time (at least one).
+// The Runs aren't limited by the Concurrency of the Job, the ones still queued when the Runner stops are cancelled.
+func (r *Runner) Backfill(j job.Job, runs []job.Run, parallelism int) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		r.cancelQueued(runs)
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		sem := make(chan struct{}, parallelism)
+		for ix, run := range runs {
+			select {
+			case sem <- struct{}{}:
+			case <-r.ctx.Done():
+				r.cancelQueued(runs[ix:])
+				return
+			case <-r.stopping:
+				r.cancelQueued(runs[ix:])
+				return
+			}
+
+			wg.Add(1)
+			go func(run job.Run) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				r.attempts(r.ctx, j, run)
+			}(run)
+		}
+	}()
+}
+
+// cancelQueued records the queued Runs that never start
+func (r *Runner) cancelQueued(runs []job.Run) {
+	for _, run := range runs {
+		run.Transition(job.StatusCancelled, time.Now())
+		r.save(run)
+	}
+}
+
+// release frees the slot of the finished Run and starts the queued one, if any.
+// The queued Runs are cancelled once the Runner stops.
+func (r *Runner) release(jobID string, slot *running) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.slots[jobID]
+	for ix, other := range s.running {
+		if other == slot {
+			s.running = append(s.running[:ix], s.running[ix+1:]...)
+			break
+		}
+	}
+
+	for len(s.queued) > 0 {
+		next := s.queued[0]
+		if r.stopped || r.ctx.Err() != nil {
+			s.queued = s.queued[1:]
+			next.run.Transition(job.StatusCancelled, time.Now())
+			r.save(next.run)
+			continue
+		}
+		if limit := next.job.Config.Concurrency.Limit(); limit > 0 && len(s.running) >= limit {
+			break
+		}
+		s.queued = s.queued[1:]
+		r.start(s, next.job, next.run)
+	}
+
+	if len(s.running) == 0 && len(s.queued) == 0 {
+		delete(r.slots, jobID)
+	}
+}
+
+// execute runs the command of the Job, its output goes to the Logs.
+// The Run cancelled in the store while it was waiting isn't started at all.
+func (r *Runner) execute(ctx context.Context, j job.Job, run job.Run) executor.Result {
+	// the stored Run is checked under the same lock as Cancel, the Run is either cancelled before or tracked
+	r.mu.Lock()
+	if stored, err := r.runs.GetRun(run.ID); err == nil && stored.Status.Final() {
+		r.mu.Unlock()
+		return executor.Result{Run: stored}
+	}
+	ctx, h := r.trackLocked(ctx, run.ID)
+	r.mu.Unlock()
+	defer r.untrack(run.ID, h)
+
+	exec := r.executor
+	exec.Started = r.save
+
+	if r.Logs != nil {
+		output, err := r.Logs.Create(run.ID)
+		if err != nil {
+			log.Printf("Run %s of job %s: %v", run.ID, run.JobID, err)
+		} else {
+			defer output.Close()
+			exec.Stdout = withLog(output, exec.Stdout)
+			exec.Stderr = withLog(output, exec.Stderr)
+			// the output is kept by the log, Result doesn't hold a copy of it
+			exec.Capture = -1
+		}
+	}
+
+	res, err := exec.Execute(ctx, j, run)
+	if res.Run.Status == job.StatusCancelled && res.Run.CancelledBy == "" {
+		res.Run.CancelledBy = r.cancelledBy(h)
+	}
+	r.save(res.Run)
+	r.Done(res, err)
+	return res
+}
+
+// retry queues the next attempt of the failed Run and waits for the delay given by the RetryPolicy,
+// the attempt is cancelled if the context ends or the attempt is cancelled in the meantime
+func (r *Runner) retry(ctx context.Context, j job.Job, failed job.Run) job.Run {
+	next := failed.NextAttempt(store.NewID())
+	next.Transition(job.StatusQueued, time.Now())
+	r.save(next)
+
+	ctx, h := r.track(ctx, next.ID)
+	defer r.untrack(next.ID, h)
+
+	timer := time.NewTimer(j.Config.Retry.Delay(failed.AttemptNumber(), r.random))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return next
+	case <-ctx.Done():
+		next.CancelledBy = r.cancelledBy(h)
+	case <-r.stopping:
+	}
+	next.Transition(job.StatusCancelled, time.Now())
+	r.save(next)
+	return next
+}
+
+// handle of the Run running or waiting for its retry, see Cancel
+type handle struct {
+	cancel context.CancelFunc
+	// by is who cancelled the Run
+	by string
+	// done is closed once the Run stops and is saved
+	done chan struct{}
+}
+
+// track registers the Run to be cancelled by Cancel, the returned context is cancelled then
+func (r *Runner) track(ctx context.Context, runID string) (context.Context, *handle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.trackLocked(ctx, runID)
+}
+
+// trackLocked is track with r.mu held
+func (r *Runner) trackLocked(ctx context.Context, runID string) (context.Context, *handle) {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &handle{cancel: cancel, done: make(chan struct{})}
+	r.handles[runID] = h
+	return ctx, h
+}
+
+// untrack forgets the stopped Run
+func (r *Runner) untrack(runID string, h *handle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handles[runID] == h {
+		delete(r.handles, runID)
+	}
+	h.cancel()
+	close(h.done)
+}
+
+func (r *Runner) cancelledBy(h *handle) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return h.by
+}
+
+// Cancel stops the Run running or waiting for its retry, the command gets SIGTERM and is killed after the grace period.
+// The Run that hasn't started yet (e.g. queued) is cancelled in the store right away and never starts.
+// The returned channel is closed once the cancelled Run is saved, false means the Run isn't known to the Runner.
+func (r *Runner) Cancel(runID, actor string) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if h, ok := r.handles[runID]; ok {
+		h.by = actor
+		h.cancel()
+		return h.done, true
+	}
+
+	// execute checks the stored Run under r.mu before it starts, so the Run can't start in the meantime
+	run, err := r.runs.GetRun(runID)
+	if err != nil || run.Status == job.StatusRunning || run.Status.Final() {
+		return nil, false
+	}
+	run.CancelledBy = actor
+	if err := run.Transition(job.StatusCancelled, time.Now()); err != nil {
+		return nil, false
+	}
+	r.save(run)
+	r.dequeue(run)
+
+	done := make(chan struct{})
+	close(done)
+	return done, true
+}
+
+// dequeue removes the cancelled Run from the queue of its Job, with r.mu held
+func (r *Runner) dequeue(run job.Run) {
+	s, ok := r.slots[run.JobID]
+	if !ok {
+		return
+	}
+	for ix, q := range s.queued {
+		if q.run.ID == run.ID {
+			s.queued = append(s.queued[:ix], s.queued[ix+1:]...)
+			break
+		}
+	}
+	if len(s.running) == 0 && len(s.queued) == 0 {
+		delete(r.slots, run.JobID)
+	}
+}
+
+// withLog copies the output to the log as well
+func withLog(output io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return output
+	}
+	return io.MultiWriter(output, w)
+}
+
+// save stores the Run, the failures are only logged as the run goes on regardless
+func (r *Runner) save(run job.Run) {
+	if err := r.runs.SaveRun(run); err != nil {
+		log.Printf("Run %s of job %s: %v", run.ID, run.JobID, err)
+	}
+}
+
+// Stop makes the Runner refuse the new Runs: the dispatched ones are skipped and the queued ones
+// (including the backfill and the retries waiting for their delay) are cancelled. The running commands go on,
+// see Wait, cancelling the context of the Runner kills them. It's called once the Scheduler stops.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.stopped = true
+	close(r.stopping)
+
+	for jobID, s := range r.slots {
+		for _, q := range s.queued {
+			r.cancelQueued([]job.Run{q.run})
+		}
+		s.queued = nil
+		if len(s.running) == 0 {
+			delete(r.slots, jobID)
+		}
+	}
+}
+
+// Wait blocks until all the dispatched runs finish, nothing may be dispatched in the meantime,
+// i.e. the Runner is stopped first unless the caller dispatches nothing else
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
@@ -0,0 +1,357 @@
+// Package scheduler triggers the runs of the active jobs at the times given by their schedules.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/store"
+)
+
+// Dispatcher starts the planned Run of the fired Job, it must not block the Scheduler
+type Dispatcher interface {
+	Dispatch(j job.Job, run job.Run)
+}
+
+// DispatcherFunc is the function used as a Dispatcher
+type DispatcherFunc func(j job.Job, run job.Run)
+
+// Dispatch calls the function
+func (f DispatcherFunc) Dispatch(j job.Job, run job.Run) {
+	f(j, run)
+}
+
+// Scheduler keeps the next fire times of all the active jobs in a min-heap,
+// sleeps until the earliest one and dispatches the jobs that are due.
+// Every job has a number of its following runs planned ahead.
+// The jobs depending on others are dispatched once the runs of their upstream jobs finish, see Finished.
+type Scheduler struct {
+	clock      Clock
+	dispatch   Dispatcher
+	numPlanned int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	queue   queue
+	// wake interrupts the sleep of Run once the jobs change
+	wake chan struct{}
+
+	// outcomes of the finished runs and the dependent runs triggered by them, see Finished
+	outcomes  map[outcome]result
+	triggered map[outcome]time.Time
+
+	// Skipped records the due Run skipped by the Pause of its Job, if set.
+	// The recorded skipped runs are the last scheduled runs of CatchUp, the paused fires aren't caught up then.
+	Skipped func(j job.Job, run job.Run)
+	// Runs keeps the planned runs, if set: the new ones are saved, the ones no longer planned are deleted
+	// and the jobs added again (e.g. after a restart) keep the IDs of their stored planned runs
+	Runs store.RunStore
+}
+
+// entry is a single job in the Scheduler
+type entry struct {
+	job job.Job
+	// planned are the following runs ordered by their fire time, the entry is queued by the first one
+	planned []job.Run
+	// last is the last dispatched fire time, the job never fires twice at the same time
+	last time.Time
+	// index within the queue, -1 if the job won't fire
+	index int
+}
+
+// New prepares the Scheduler with no jobs, planning numPlanned runs of every job (at least one)
+func New(clock Clock, dispatch Dispatcher, numPlanned int) *Scheduler {
+	if numPlanned < 1 {
+		numPlanned = 1
+	}
+	return &Scheduler{
+		clock:      clock,
+		dispatch:   dispatch,
+		numPlanned: numPlanned,
+		entries:    make(map[string]*entry),
+		wake:       make(chan struct{}, 1),
+		outcomes:   make(map[outcome]result),
+		triggered:  make(map[outcome]time.Time),
+	}
+}
+
+// Load adds all the jobs to the Scheduler
+func (s *Scheduler) Load(jobs []job.Job) {
+	for _, j := range jobs {
+		s.Upsert(j)
+	}
+}
+
+// Upsert adds the new Job or replaces the stored one with the same ID and plans its next fire time
+func (s *Scheduler) Upsert(j job.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[j.ID]
+	if !ok {
+		e = &entry{index: -1, planned: s.stored(j.ID)}
+		s.entries[j.ID] = e
+	}
+	e.job = j
+	s.plan(e, s.clock.Now())
+	s.notify()
+}
+
+// Remove deletes the Job from the Scheduler
+func (s *Scheduler) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	if e.index >= 0 {
+		heap.Remove(&s.queue, e.index)
+	}
+	s.forget(e.planned)
+	delete(s.entries, id)
+	s.notify()
+}
+
+// Next returns the next fire time of the Job, false if the job won't fire
+func (s *Scheduler) Next(id string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok || e.index < 0 {
+		return time.Time{}, false
+	}
+	return e.next(), true
+}
+
+// Planned returns the following runs of the Job, false if the Job is unknown
+func (s *Scheduler) Planned(id string) ([]job.Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return append([]job.Run{}, e.planned...), true
+}
+
+// notify wakes up Run without blocking, a single pending notification is enough
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// plan finds the next fire times of the entry from now on and (re)places it in the queue,
+// inactive and exhausted jobs are taken out of the queue.
+// The runs planned already are kept as long as their fire times stay planned, the ones covered by the Pause of the job are skipped.
+func (s *Scheduler) plan(e *entry, now time.Time) {
+	after := now
+	if !e.last.IsZero() && !after.After(e.last) {
+		after = e.last.Add(time.Second)
+	}
+
+	times, err := e.job.Schedule.Times(after, time.Time{}, s.numPlanned)
+	if !e.job.Active || err != nil || len(times) == 0 {
+		s.forget(e.planned)
+		e.planned = nil
+		if e.index >= 0 {
+			heap.Remove(&s.queue, e.index)
+		}
+		return
+	}
+
+	old := make(map[int64]job.Run, len(e.planned))
+	for _, run := range e.planned {
+		old[run.ScheduledTime.Unix()] = run
+	}
+	planned := make([]job.Run, 0, len(times))
+	for _, t := range times {
+		// the skipped run is final, the resumed job plans a new one
+		run, ok := old[t.Unix()]
+		changed := false
+		if !ok || (run.Status == job.StatusSkipped && !e.job.Paused(t)) {
+			run = job.Run{ID: store.NewID(), JobID: e.job.ID, ScheduledTime: t, Trigger: job.TriggerSchedule, Attempt: 1}
+			run.Transition(job.StatusPlanned, now)
+			changed = true
+		} else {
+			delete(old, t.Unix())
+		}
+		if e.job.Paused(t) && run.Status == job.StatusPlanned {
+			run.Transition(job.StatusSkipped, now)
+			changed = true
+		}
+		if changed {
+			s.save(run)
+		}
+		planned = append(planned, run)
+	}
+
+	stale := make([]job.Run, 0, len(old))
+	for _, run := range old {
+		stale = append(stale, run)
+	}
+	s.forget(stale)
+	e.planned = planned
+	if e.index >= 0 {
+		heap.Fix(&s.queue, e.index)
+		return
+	}
+	heap.Push(&s.queue, e)
+}
+
+// due takes the jobs whose fire time has come and plans their following fire times,
+// the fire times missed while sleeping are dispatched only once and the skipped ones (of the paused jobs) are only recorded, see Run.
+// It returns the duration until the next fire time, negative if there is none.
+func (s *Scheduler) due(now time.Time) ([]firing, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fired := make([]firing, 0)
+	for len(s.queue) > 0 && !s.queue[0].next().After(now) {
+		e := s.queue[0]
+		fired = append(fired, firing{job: e.job, run: e.planned[0]})
+		e.last = e.next()
+		// the fired run is saved by the Dispatcher from now on, it isn't stale
+		e.planned = e.planned[1:]
+		s.plan(e, now)
+	}
+
+	if len(s.queue) == 0 {
+		return fired, -1
+	}
+	return fired, s.queue[0].next().Sub(now)
+}
+
+// stored lists the planned runs of the Job kept by the Runs, the ones never started.
+// They are listed from the oldest start, i.e. the ones never started come first.
+func (s *Scheduler) stored(jobID string) []job.Run {
+	if s.Runs == nil {
+		return nil
+	}
+
+	planned := make([]job.Run, 0, s.numPlanned)
+	filter := store.RunFilter{JobID: jobID, Status: []job.Status{job.StatusPlanned, job.StatusSkipped},
+		Trigger: []job.Trigger{job.TriggerSchedule}, Ascending: true, Limit: s.numPlanned}
+	for {
+		page, err := s.Runs.ListRuns(filter)
+		if err != nil {
+			log.Printf("Planned runs of job %s: %v", jobID, err)
+			return planned
+		}
+		for _, run := range page.Runs {
+			// the skipped runs recorded once due have started and ended at once
+			if !run.StartTime.IsZero() {
+				return planned
+			}
+			planned = append(planned, run)
+		}
+		if page.Next == "" {
+			return planned
+		}
+		filter.Cursor = page.Next
+	}
+}
+
+// save stores the planned Run, the failures are only logged as the Run stays planned in the memory
+func (s *Scheduler) save(run job.Run) {
+	if s.Runs == nil {
+		return
+	}
+	if err := s.Runs.SaveRun(run); err != nil {
+		log.Printf("Run %s of job %s: %v", run.ID, run.JobID, err)
+	}
+}
+
+// forget deletes the stored planned Runs that are no longer planned
+func (s *Scheduler) forget(runs []job.Run) {
+	if s.Runs == nil {
+		return
+	}
+	for _, run := range runs {
+		if err := s.Runs.DeleteRun(run.ID); err != nil && err != store.ErrNotFound {
+			log.Printf("Run %s of job %s: %v", run.ID, run.JobID, err)
+		}
+	}
+}
+
+// firing is the planned Run of the Job that is due
+type firing struct {
+	job job.Job
+	run job.Run
+}
+
+// next is the fire time of the first planned run
+func (e *entry) next() time.Time {
+	return e.planned[0].ScheduledTime
+}
+
+// Run dispatches the jobs until the context is cancelled, the skipped runs are passed to Skipped instead
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		fired, wait := s.due(s.clock.Now())
+		for _, f := range fired {
+			if f.run.Status != job.StatusSkipped {
+				s.dispatch.Dispatch(f.job, f.run)
+			} else if s.Skipped != nil {
+				s.Skipped(f.job, f.run)
+			}
+		}
+
+		var timer Timer
+		var expired <-chan time.Time
+		if wait >= 0 {
+			timer = s.clock.NewTimer(wait)
+			expired = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-s.wake:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// queue is the min-heap of the entries ordered by their next fire time, see container/heap
+type queue []*entry
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool { return q[i].next().Before(q[j].next()) }
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,864 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/executor"
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/logs"
+	"github.com/kubistmi/plango/schedule"
+	"github.com/kubistmi/plango/store"
+)
+
+// fired is a single dispatch recorded by the tests
+type fired struct {
+	ID string
+	At time.Time
+}
+
+func testJob(t *testing.T, id, sch string) job.Job {
+	t.Helper()
+	s, err := schedule.ParseSchedule(sch)
+	if err != nil {
+		t.Fatalf("Unable to parse the schedule %s: %v", sch, err)
+	}
+	return job.Job{ID: id, Name: id, Schedule: s, Active: true, Command: "true"}
+}
+
+// collect runs the due jobs and records the dispatched ones
+func collect(s *Scheduler, now time.Time) ([]fired, time.Duration) {
+	due, wait := s.due(now)
+	res := make([]fired, 0, len(due))
+	for _, f := range due {
+		if f.run.Status != job.StatusSkipped {
+			res = append(res, fired{ID: f.job.ID, At: f.run.ScheduledTime})
+		}
+	}
+	return res, wait
+}
+
+func TestDue(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2020, 1, day, hour, min, 0, 0, time.Local)
+	}
+
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	hourly := testJob(t, "hourly", "0 30 * * * *")
+	inactive := testJob(t, "inactive", "0 0 6 * * *")
+	inactive.Active = false
+	bounded := testJob(t, "bounded", "0 0 6 * * *")
+	bounded.Schedule, _ = bounded.Schedule.Bounded(time.Time{}, at(2, 12, 0))
+
+	tests := map[string]struct {
+		jobs []job.Job
+		now  time.Time
+		want []fired
+		wait time.Duration
+	}{
+		"empty": {
+			jobs: []job.Job{}, now: at(1, 6, 0),
+			want: []fired{}, wait: -1,
+		},
+		"not yet": {
+			jobs: []job.Job{daily}, now: at(1, 5, 0),
+			want: []fired{}, wait: time.Hour,
+		},
+		"daily": {
+			jobs: []job.Job{daily}, now: at(1, 6, 0),
+			want: []fired{{"daily", at(1, 6, 0)}}, wait: 24 * time.Hour,
+		},
+		"missed fires only once": {
+			jobs: []job.Job{daily}, now: at(3, 7, 0),
+			want: []fired{{"daily", at(1, 6, 0)}}, wait: 23 * time.Hour,
+		},
+		"ordered": {
+			jobs: []job.Job{daily, hourly}, now: at(1, 6, 0),
+			want: []fired{{"hourly", at(1, 0, 30)}, {"daily", at(1, 6, 0)}}, wait: 30 * time.Minute,
+		},
+		"inactive": {
+			jobs: []job.Job{inactive}, now: at(1, 6, 0),
+			want: []fired{}, wait: -1,
+		},
+		"bounded": {
+			jobs: []job.Job{bounded}, now: at(3, 6, 0),
+			want: []fired{{"bounded", at(1, 6, 0)}}, wait: -1,
+		},
+	}
+
+	for name, tc := range tests {
+		s := New(newFakeClock(start), DispatcherFunc(func(job.Job, job.Run) {}), 1)
+		s.Load(tc.jobs)
+
+		got, wait := collect(s, tc.now)
+		if !reflect.DeepEqual(tc.want, got) || tc.wait != wait {
+			t.Fatalf("%s: Expected: %#v, %v, got: %#v, %v", name, tc.want, tc.wait, got, wait)
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	s := New(newFakeClock(start), DispatcherFunc(func(job.Job, job.Run) {}), 1)
+
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	s.Upsert(daily)
+	if next, ok := s.Next("daily"); !ok || !next.Equal(start.Add(6*time.Hour)) {
+		t.Fatalf("Expected: %v, got: %v %v", start.Add(6*time.Hour), next, ok)
+	}
+
+	// the update replans the job
+	evening := testJob(t, "daily", "0 0 18 * * *")
+	s.Upsert(evening)
+	if next, ok := s.Next("daily"); !ok || !next.Equal(start.Add(18*time.Hour)) {
+		t.Fatalf("Expected: %v, got: %v %v", start.Add(18*time.Hour), next, ok)
+	}
+
+	// the job fired already doesn't fire again at the same time
+	got, _ := collect(s, start.Add(18*time.Hour))
+	if len(got) != 1 {
+		t.Fatalf("Expected: 1 fire, got: %#v", got)
+	}
+	s.Upsert(evening)
+	if next, ok := s.Next("daily"); !ok || !next.Equal(start.Add(42*time.Hour)) {
+		t.Fatalf("Expected: %v, got: %v %v", start.Add(42*time.Hour), next, ok)
+	}
+
+	// deactivation unschedules the job
+	evening.Active = false
+	s.Upsert(evening)
+	if next, ok := s.Next("daily"); ok {
+		t.Fatalf("Expected: no fire time, got: %v", next)
+	}
+
+	s.Upsert(daily)
+	s.Remove("daily")
+	if next, ok := s.Next("daily"); ok {
+		t.Fatalf("Expected: no fire time, got: %v", next)
+	}
+	if got, wait := collect(s, start.Add(48*time.Hour)); len(got) != 0 || wait != -1 {
+		t.Fatalf("Expected: no fires, got: %#v, %v", got, wait)
+	}
+}
+
+func TestPlanned(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 6, 0, 0, 0, time.Local)
+	}
+	times := func(runs []job.Run) []time.Time {
+		res := make([]time.Time, 0, len(runs))
+		for _, run := range runs {
+			if run.JobID != "daily" || run.Status != job.StatusPlanned || run.Trigger != job.TriggerSchedule {
+				t.Fatalf("Unexpected run: %#v", run)
+			}
+			res = append(res, run.ScheduledTime)
+		}
+		return res
+	}
+
+	s := New(newFakeClock(start), DispatcherFunc(func(job.Job, job.Run) {}), 3)
+	if _, ok := s.Planned("daily"); ok {
+		t.Fatalf("Expected: unknown job")
+	}
+
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	s.Upsert(daily)
+	before, _ := s.Planned("daily")
+	if want := []time.Time{day(1), day(2), day(3)}; !reflect.DeepEqual(want, times(before)) {
+		t.Fatalf("Expected: %v, got: %v", want, times(before))
+	}
+
+	// the fired run is replaced by the following one, the rest is kept
+	due, _ := s.due(day(1))
+	after, _ := s.Planned("daily")
+	if want := []time.Time{day(2), day(3), day(4)}; !reflect.DeepEqual(want, times(after)) {
+		t.Fatalf("Expected: %v, got: %v", want, times(after))
+	}
+	if len(due) != 1 || due[0].run.ID != before[0].ID || after[0].ID != before[1].ID || after[1].ID != before[2].ID {
+		t.Fatalf("Expected the IDs to be kept, got: %#v, %#v, %#v", before, due, after)
+	}
+
+	// the runs left out by the edit are dropped
+	daily.Schedule, _ = daily.Schedule.Bounded(time.Time{}, day(3))
+	s.Upsert(daily)
+	bounded, _ := s.Planned("daily")
+	if want := []time.Time{day(2), day(3)}; !reflect.DeepEqual(want, times(bounded)) {
+		t.Fatalf("Expected: %v, got: %v", want, times(bounded))
+	}
+
+	daily.Active = false
+	s.Upsert(daily)
+	if inactive, ok := s.Planned("daily"); !ok || len(inactive) != 0 {
+		t.Fatalf("Expected: no runs, got: %#v", inactive)
+	}
+}
+
+func TestPlannedStored(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 6, 0, 0, 0, time.Local)
+	}
+	runs := store.NewMemory()
+	stored := func() []job.Run {
+		page, _ := runs.ListRuns(store.RunFilter{Status: []job.Status{job.StatusPlanned}, Ascending: true})
+		return page.Runs
+	}
+	ids := func(runs []job.Run) map[string]bool {
+		res := make(map[string]bool, len(runs))
+		for _, run := range runs {
+			res[run.ID] = true
+		}
+		return res
+	}
+
+	s := New(newFakeClock(start), DispatcherFunc(func(job.Job, job.Run) {}), 3)
+	s.Runs = runs
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	s.Upsert(daily)
+	planned, _ := s.Planned("daily")
+	if want, got := ids(planned), ids(stored()); len(want) != 3 || !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected: %v, got: %v", want, got)
+	}
+
+	// the restarted scheduler keeps the IDs
+	restarted := New(newFakeClock(start), DispatcherFunc(func(job.Job, job.Run) {}), 3)
+	restarted.Runs = runs
+	restarted.Load([]job.Job{daily})
+	if again, _ := restarted.Planned("daily"); !reflect.DeepEqual(planned, again) {
+		t.Fatalf("Expected: %#v, got: %#v", planned, again)
+	}
+
+	// the fired run is left to the dispatcher, the runs left out by the edit are deleted
+	restarted.due(day(1))
+	daily.Schedule, _ = daily.Schedule.Bounded(time.Time{}, day(3))
+	restarted.Upsert(daily)
+	bounded, _ := restarted.Planned("daily")
+	if want, got := ids(append(bounded, planned[0])), ids(stored()); len(bounded) != 2 || !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected: %v, got: %v", want, got)
+	}
+
+	restarted.Remove("daily")
+	if left := stored(); len(left) != 1 || left[0].ID != planned[0].ID {
+		t.Fatalf("Expected only the fired run, got: %#v", left)
+	}
+}
+
+// waitTimers blocks until Run sleeps on the given number of timers
+func waitTimers(t *testing.T, clock *fakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.waiting() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected: %v timers, got: %v", n, clock.waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectFire(t *testing.T, dispatched <-chan fired, want *fired) {
+	t.Helper()
+	if want == nil {
+		select {
+		case got := <-dispatched:
+			t.Fatalf("Expected: no fire, got: %#v", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return
+	}
+
+	select {
+	case got := <-dispatched:
+		if !reflect.DeepEqual(*want, got) {
+			t.Fatalf("Expected: %#v, got: %#v", *want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected: %#v, got: no fire", *want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	clock := newFakeClock(start)
+	dispatched := make(chan fired, 10)
+	s := New(clock, DispatcherFunc(func(j job.Job, run job.Run) {
+		dispatched <- fired{ID: j.ID, At: run.ScheduledTime}
+	}), 1)
+	s.Upsert(testJob(t, "daily", "0 0 6 * * *"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	waitTimers(t, clock, 1)
+	clock.Advance(6 * time.Hour)
+	expectFire(t, dispatched, &fired{ID: "daily", At: start.Add(6 * time.Hour)})
+
+	waitTimers(t, clock, 1)
+	clock.Advance(24 * time.Hour)
+	expectFire(t, dispatched, &fired{ID: "daily", At: start.Add(30 * time.Hour)})
+
+	// the removal wakes up the sleeping loop
+	waitTimers(t, clock, 1)
+	s.Remove("daily")
+	waitTimers(t, clock, 0)
+	clock.Advance(24 * time.Hour)
+	expectFire(t, dispatched, nil)
+
+	// so does the creation
+	clock.Advance(30 * time.Minute)
+	s.Upsert(testJob(t, "hourly", "0 0 * * * *"))
+	waitTimers(t, clock, 1)
+	clock.Advance(time.Hour)
+	expectFire(t, dispatched, &fired{ID: "hourly", At: start.Add(55 * time.Hour)})
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	at := time.Date(2020, 1, 1, 6, 0, 0, 0, time.Local)
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	done := make(chan job.Run, 1)
+	runner.Done = func(res executor.Result, err error) {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		done <- res.Run
+	}
+
+	output, _ := logs.OpenDir(t.TempDir())
+	runner.Logs = output
+
+	j := testJob(t, "daily", "0 0 6 * * *")
+	j.Command, j.Args = "echo", []string{"hello"}
+	runner.Dispatch(j, job.Run{ID: "run", JobID: "daily", ScheduledTime: at, Trigger: job.TriggerSchedule})
+	runner.Wait()
+
+	got := <-done
+	if got.ID != "run" || got.JobID != "daily" || !got.ScheduledTime.Equal(at) ||
+		got.Trigger != job.TriggerSchedule || got.Status != job.StatusSucceeded {
+		t.Fatalf("Unexpected run: %#v", got)
+	}
+	if saved, err := runs.GetRun("run"); err != nil || !reflect.DeepEqual(got, saved) {
+		t.Fatalf("Expected: %#v, got: %#v %v", got, saved, err)
+	}
+	if data, err := output.Read("run"); err != nil || string(data) != "hello\n" {
+		t.Fatalf("Expected: %q, got: %q %v", "hello\n", data, err)
+	}
+}
+
+func TestRunnerRetry(t *testing.T) {
+	at := time.Date(2020, 1, 1, 6, 0, 0, 0, time.Local)
+	j := testJob(t, "flaky", "0 0 6 * * *")
+	j.Command = "false"
+	j.Config.Retry = &job.RetryPolicy{MaxAttempts: 3, InitialDelay: job.Duration(time.Millisecond)}
+
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	runner.Done = func(executor.Result, error) {}
+	var finished []job.Run
+	runner.Finished = func(_ job.Job, run job.Run) { finished = append(finished, run) }
+	runner.Dispatch(j, job.Run{ID: "first", JobID: j.ID, ScheduledTime: at, Trigger: job.TriggerSchedule, Attempt: 1})
+	runner.Wait()
+
+	if len(finished) != 1 || finished[0].Attempt != 3 || finished[0].Status != job.StatusFailed {
+		t.Fatalf("Expected the last attempt to finish, got: %#v", finished)
+	}
+
+	page, _ := runs.ListRuns(store.RunFilter{})
+	if len(page.Runs) != 3 {
+		t.Fatalf("Expected: 3 attempts, got: %#v", page.Runs)
+	}
+	for _, run := range page.Runs {
+		if run.Status != job.StatusFailed || !run.ScheduledTime.Equal(at) {
+			t.Fatalf("Unexpected attempt: %#v", run)
+		}
+		if run.ID == "first" {
+			continue
+		}
+		if run.Trigger != job.TriggerRetry || run.RetryOf != "first" || run.Attempt < 2 {
+			t.Fatalf("Unexpected retry: %#v", run)
+		}
+	}
+}
+
+func TestRunnerRetryCancelled(t *testing.T) {
+	j := testJob(t, "flaky", "0 0 6 * * *")
+	j.Command = "false"
+	j.Config.Retry = &job.RetryPolicy{MaxAttempts: 3, InitialDelay: job.Duration(time.Hour)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := store.NewMemory()
+	runner := NewRunner(ctx, executor.Executor{}, runs)
+	runner.Done = func(executor.Result, error) {}
+	runner.Dispatch(j, job.Run{ID: "first", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+
+	// the retry waits for its delay
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		page, _ := runs.ListRuns(store.RunFilter{Status: []job.Status{job.StatusQueued}})
+		if len(page.Runs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the queued retry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	runner.Wait()
+
+	page, _ := runs.ListRuns(store.RunFilter{Trigger: []job.Trigger{job.TriggerRetry}})
+	if len(page.Runs) != 1 || page.Runs[0].Status != job.StatusCancelled || page.Runs[0].Attempt != 2 {
+		t.Fatalf("Expected the cancelled retry, got: %#v", page.Runs)
+	}
+}
+
+func TestRunnerConcurrency(t *testing.T) {
+	tests := map[string]struct {
+		concurrency job.Concurrency
+		want        []job.Status
+		sequential  bool
+	}{
+		"allow":         {concurrency: job.Concurrency{}, want: []job.Status{job.StatusSucceeded, job.StatusSucceeded}},
+		"allow limited": {concurrency: job.Concurrency{Policy: job.ConcurrencyAllow, MaxParallel: 1}, want: []job.Status{job.StatusSucceeded, job.StatusSucceeded}, sequential: true},
+		"forbid":        {concurrency: job.Concurrency{Policy: job.ConcurrencyForbid}, want: []job.Status{job.StatusSucceeded, job.StatusSkipped}},
+		"replace":       {concurrency: job.Concurrency{Policy: job.ConcurrencyReplace}, want: []job.Status{job.StatusCancelled, job.StatusSucceeded}},
+		"queue":         {concurrency: job.Concurrency{Policy: job.ConcurrencyQueue}, want: []job.Status{job.StatusSucceeded, job.StatusSucceeded}, sequential: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			j := testJob(t, "slow", "0 0 6 * * *")
+			j.Command, j.Args = "sleep", []string{"0.2"}
+			j.Config.Concurrency = test.concurrency
+
+			runs := store.NewMemory()
+			runner := NewRunner(context.Background(), executor.Executor{}, runs)
+			runner.Done = func(executor.Result, error) {}
+			runner.Dispatch(j, job.Run{ID: "first", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+			runner.Dispatch(j, job.Run{ID: "second", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+			runner.Wait()
+
+			first, _ := runs.GetRun("first")
+			second, _ := runs.GetRun("second")
+			if got := []job.Status{first.Status, second.Status}; !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %#v, got: %#v", test.want, got)
+			}
+			if test.sequential && second.StartTime.Before(first.EndTime) {
+				t.Fatalf("Expected the second run to start after the first one: %#v %#v", first, second)
+			}
+			if test.sequential && second.History[0].To != job.StatusQueued {
+				t.Fatalf("Expected the queued run, got: %#v", second.History)
+			}
+		})
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2020, 1, d, h, 0, 0, 0, time.Local)
+	}
+	first := job.Run{ID: "first", JobID: "daily", ScheduledTime: day(1, 6), StartTime: day(1, 6), Trigger: job.TriggerSchedule}
+	manual := job.Run{ID: "manual", JobID: "daily", ScheduledTime: day(5, 7), StartTime: day(5, 7), Trigger: job.TriggerManual}
+	last := job.Run{ID: "last", JobID: "daily", ScheduledTime: day(5, 6), StartTime: day(5, 6), Trigger: job.TriggerSchedule}
+	queued := job.Run{ID: "queued", JobID: "daily", ScheduledTime: day(5, 6), Trigger: job.TriggerSchedule, Status: job.StatusQueued}
+	planned := job.Run{ID: "planned", JobID: "daily", ScheduledTime: day(6, 6), Trigger: job.TriggerSchedule, Status: job.StatusPlanned}
+
+	tests := map[string]struct {
+		misfire  job.Misfire
+		recorded []job.Run
+		want     []time.Time
+	}{
+		"skip":         {misfire: job.Misfire{Policy: job.MisfireSkip}, recorded: []job.Run{first}, want: []time.Time{}},
+		"once":         {misfire: job.Misfire{Policy: job.MisfireOnce}, recorded: []job.Run{first}, want: []time.Time{day(5, 6)}},
+		"all":          {misfire: job.Misfire{Policy: job.MisfireAll}, recorded: []job.Run{first}, want: []time.Time{day(2, 6), day(3, 6), day(4, 6), day(5, 6)}},
+		"all limited":  {misfire: job.Misfire{Policy: job.MisfireAll, MaxRuns: 2}, recorded: []job.Run{first}, want: []time.Time{day(4, 6), day(5, 6)}},
+		"deadline":     {misfire: job.Misfire{Policy: job.MisfireAll, Deadline: job.Duration(30 * time.Hour)}, recorded: []job.Run{first}, want: []time.Time{day(4, 6), day(5, 6)}},
+		"manual run":   {misfire: job.Misfire{Policy: job.MisfireAll}, recorded: []job.Run{first, manual}, want: []time.Time{day(2, 6), day(3, 6), day(4, 6), day(5, 6)}},
+		"never run":    {misfire: job.Misfire{Policy: job.MisfireAll}, want: []time.Time{}},
+		"nothing lost": {misfire: job.Misfire{Policy: job.MisfireAll}, recorded: []job.Run{first, last}, want: []time.Time{}},
+		"queued last":  {misfire: job.Misfire{Policy: job.MisfireAll}, recorded: []job.Run{first, queued}, want: []time.Time{}},
+		"planned":      {misfire: job.Misfire{Policy: job.MisfireAll}, recorded: []job.Run{first, planned}, want: []time.Time{day(2, 6), day(3, 6), day(4, 6), day(5, 6)}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			runs := store.NewMemory()
+			for _, run := range test.recorded {
+				runs.SaveRun(run)
+			}
+
+			got := make([]time.Time, 0)
+			s := New(newFakeClock(day(5, 12)), DispatcherFunc(func(j job.Job, run job.Run) {
+				if j.ID != "daily" || run.Trigger != job.TriggerSchedule {
+					t.Errorf("Unexpected run: %#v", run)
+				}
+				got = append(got, run.ScheduledTime)
+			}), 1)
+
+			daily := testJob(t, "daily", "0 0 6 * * *")
+			daily.Misfire = test.misfire
+			s.Load([]job.Job{daily})
+			if err := s.CatchUp(runs); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Fatalf("Expected: %v, got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRunnerStop(t *testing.T) {
+	j := testJob(t, "slow", "0 0 6 * * *")
+	j.Command, j.Args = "sleep", []string{"0.2"}
+	j.Config.Concurrency = job.Concurrency{Policy: job.ConcurrencyQueue}
+
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	runner.Done = func(executor.Result, error) {}
+	runner.Dispatch(j, job.Run{ID: "running", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	runner.Dispatch(j, job.Run{ID: "queued", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	waitStatus(t, runs, "running", job.StatusRunning)
+
+	runner.Stop()
+	runner.Dispatch(j, job.Run{ID: "late", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	runner.Wait()
+
+	want := map[string]job.Status{"running": job.StatusSucceeded, "queued": job.StatusCancelled, "late": job.StatusSkipped}
+	for id, status := range want {
+		if got, _ := runs.GetRun(id); got.Status != status {
+			t.Fatalf("Expected: %v, got: %#v", status, got)
+		}
+	}
+}
+
+func TestRunnerRecover(t *testing.T) {
+	now := time.Now()
+	runs := store.NewMemory()
+	queued := job.Run{ID: "queued", JobID: "report", Trigger: job.TriggerManual}
+	queued.Transition(job.StatusQueued, now)
+	running := job.Run{ID: "running", JobID: "report", StartTime: now, Trigger: job.TriggerSchedule}
+	running.Transition(job.StatusRunning, now)
+	finished := job.Run{ID: "finished", JobID: "report", StartTime: now, EndTime: now, Trigger: job.TriggerSchedule}
+	finished.Transition(job.StatusRunning, now)
+	finished.Transition(job.StatusSucceeded, now)
+	for _, run := range []job.Run{queued, running, finished} {
+		runs.SaveRun(run)
+	}
+	// the backfill queues more runs than are listed at once
+	for i := 0; i < recoverBatch+10; i++ {
+		run := job.Run{ID: fmt.Sprint("backfill", i), JobID: "report", ScheduledTime: now.AddDate(0, 0, -i), Trigger: job.TriggerBackfill, Attempt: 1}
+		run.Transition(job.StatusQueued, now)
+		runs.SaveRun(run)
+	}
+
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	if err := runner.Recover(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]job.Status{"queued": job.StatusCancelled, "running": job.StatusFailed, "finished": job.StatusSucceeded}
+	for id, status := range want {
+		if got, _ := runs.GetRun(id); got.Status != status || got.EndTime.IsZero() != (id == "queued") {
+			t.Fatalf("Expected: %v, got: %#v", status, got)
+		}
+	}
+	if page, _ := runs.ListRuns(store.RunFilter{Status: []job.Status{job.StatusQueued}}); len(page.Runs) != 0 {
+		t.Fatalf("Expected no queued runs, got: %#v", page.Runs)
+	}
+}
+
+func TestRunnerBackfill(t *testing.T) {
+	j := testJob(t, "slow", "0 0 6 * * *")
+	j.Command, j.Args = "sleep", []string{"0.1"}
+	j.Config.Concurrency = job.Concurrency{Policy: job.ConcurrencyForbid}
+
+	queued := make([]job.Run, 0, 5)
+	for d := 1; d <= 5; d++ {
+		run := job.Run{ID: fmt.Sprint(d), JobID: j.ID, ScheduledTime: time.Date(2020, 1, d, 6, 0, 0, 0, time.Local), Trigger: job.TriggerBackfill, Attempt: 1}
+		run.Transition(job.StatusQueued, time.Now())
+		queued = append(queued, run)
+	}
+
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	runner.Done = func(executor.Result, error) {}
+	runner.Backfill(j, queued, 2)
+	runner.Wait()
+
+	page, _ := runs.ListRuns(store.RunFilter{Ascending: true})
+	if len(page.Runs) != 5 {
+		t.Fatalf("Expected: 5 runs, got: %#v", page.Runs)
+	}
+	for ix, run := range page.Runs {
+		if run.Status != job.StatusSucceeded {
+			t.Fatalf("Unexpected run: %#v", run)
+		}
+		// the runs start in their order, the third one once one of the first two finishes
+		running := 0
+		for _, other := range page.Runs[:ix] {
+			if other.EndTime.After(run.StartTime) {
+				running++
+			}
+		}
+		if running >= 2 {
+			t.Fatalf("Expected at most 2 runs in parallel, got: %#v", page.Runs)
+		}
+	}
+}
+
+func TestFinished(t *testing.T) {
+	at := time.Date(2020, 1, 1, 6, 0, 0, 0, time.Local)
+	dependent := func(id string, condition job.Condition, upstreams ...string) job.Job {
+		j := job.Job{ID: id, Name: id, Active: true, Command: "true"}
+		j.DependsOn = &job.Dependencies{Jobs: upstreams, Condition: condition}
+		return j
+	}
+	extract := testJob(t, "extract", "0 0 6 * * *")
+	inactive := dependent("inactive", job.AllSuccess, "extract")
+	inactive.Active = false
+
+	var got []fired
+	s := New(newFakeClock(at), DispatcherFunc(func(j job.Job, run job.Run) {
+		if run.Trigger != job.TriggerDependency || run.JobID != j.ID {
+			t.Errorf("Unexpected run: %#v", run)
+		}
+		got = append(got, fired{ID: j.ID, At: run.ScheduledTime})
+	}), 1)
+	s.Load([]job.Job{extract, inactive,
+		dependent("transform", "", "extract"),
+		dependent("report", job.AllSuccess, "extract", "transform"),
+		dependent("alert", job.AnyFailed, "extract", "transform"),
+	})
+
+	finish := func(id string, status job.Status, at time.Time) []fired {
+		got = nil
+		s.Finished(job.Job{ID: id}, job.Run{JobID: id, ScheduledTime: at, Status: status})
+		return got
+	}
+
+	if want := []fired{{ID: "transform", At: at}}; !reflect.DeepEqual(want, finish("extract", job.StatusSucceeded, at)) {
+		t.Fatalf("Expected: %v, got: %v", want, got)
+	}
+	if want := []fired{{ID: "report", At: at}}; !reflect.DeepEqual(want, finish("transform", job.StatusSucceeded, at)) {
+		t.Fatalf("Expected: %v, got: %v", want, got)
+	}
+	// the dependent jobs are triggered once for the logical time
+	if got := finish("extract", job.StatusSucceeded, at); len(got) != 0 {
+		t.Fatalf("Expected: nothing, got: %v", got)
+	}
+
+	// the following logical time is independent of the previous one
+	next := at.Add(24 * time.Hour)
+	if want := []fired{{ID: "alert", At: next}}; !reflect.DeepEqual(want, finish("extract", job.StatusFailed, next)) {
+		t.Fatalf("Expected: %v, got: %v", want, got)
+	}
+	if got := finish("transform", job.StatusSucceeded, next); len(got) != 0 {
+		t.Fatalf("Expected: nothing, got: %v", got)
+	}
+}
+
+func TestPaused(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2020, 1, d, h, 0, 0, 0, time.Local)
+	}
+	statuses := func(runs []job.Run) []job.Status {
+		res := make([]job.Status, 0, len(runs))
+		for _, run := range runs {
+			res = append(res, run.Status)
+		}
+		return res
+	}
+
+	s := New(newFakeClock(day(1, 0)), DispatcherFunc(func(job.Job, job.Run) {}), 3)
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	s.Upsert(daily)
+	before, _ := s.Planned("daily")
+
+	until := day(2, 12)
+	daily.Pause = &job.Pause{Reason: "incident", At: day(1, 0), Until: &until}
+	s.Upsert(daily)
+	paused, _ := s.Planned("daily")
+	if want := []job.Status{job.StatusSkipped, job.StatusSkipped, job.StatusPlanned}; !reflect.DeepEqual(want, statuses(paused)) {
+		t.Fatalf("Expected: %v, got: %v", want, statuses(paused))
+	}
+	if paused[0].ID != before[0].ID || paused[2].ID != before[2].ID {
+		t.Fatalf("Expected the IDs to be kept, got: %#v, %#v", before, paused)
+	}
+
+	// the skipped fire isn't dispatched
+	if got, _ := collect(s, day(1, 6)); len(got) != 0 {
+		t.Fatalf("Expected: nothing, got: %v", got)
+	}
+
+	daily.Pause = nil
+	s.Upsert(daily)
+	resumed, _ := s.Planned("daily")
+	if want := []job.Status{job.StatusPlanned, job.StatusPlanned, job.StatusPlanned}; !reflect.DeepEqual(want, statuses(resumed)) {
+		t.Fatalf("Expected: %v, got: %v", want, statuses(resumed))
+	}
+	if resumed[0].ID == paused[1].ID || len(resumed[0].History) != 1 {
+		t.Fatalf("Expected a new run, got: %#v", resumed[0])
+	}
+	if got, _ := collect(s, day(2, 6)); !reflect.DeepEqual([]fired{{ID: "daily", At: day(2, 6)}}, got) {
+		t.Fatalf("Expected: %v, got: %v", []fired{{ID: "daily", At: day(2, 6)}}, got)
+	}
+}
+
+func TestPausedCatchUp(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2020, 1, d, h, 0, 0, 0, time.Local)
+	}
+	runs := store.NewMemory()
+	runs.SaveRun(job.Run{ID: "first", JobID: "daily", ScheduledTime: day(1, 6), StartTime: day(1, 6), Trigger: job.TriggerSchedule, Status: job.StatusSucceeded})
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+
+	dispatched := make(chan fired, 10)
+	dispatch := DispatcherFunc(func(j job.Job, run job.Run) {
+		dispatched <- fired{ID: j.ID, At: run.ScheduledTime}
+	})
+	clock := newFakeClock(day(1, 12))
+	s := New(clock, dispatch, 1)
+	s.Skipped = runner.Skipped
+
+	daily := testJob(t, "daily", "0 0 6 * * *")
+	daily.Misfire = job.Misfire{Policy: job.MisfireAll}
+	until := day(3, 12)
+	daily.Pause = &job.Pause{Reason: "incident", At: day(1, 12), Until: &until}
+	s.Upsert(daily)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	for d := 2; d <= 3; d++ {
+		waitTimers(t, clock, 1)
+		clock.Advance(24 * time.Hour)
+	}
+	waitTimers(t, clock, 1)
+	cancel()
+	<-done
+	expectFire(t, dispatched, nil)
+
+	// the paused fires are recorded as skipped
+	page, _ := runs.ListRuns(store.RunFilter{JobID: "daily", Status: []job.Status{job.StatusSkipped}, Ascending: true})
+	if len(page.Runs) != 2 || !page.Runs[0].ScheduledTime.Equal(day(2, 6)) || !page.Runs[1].ScheduledTime.Equal(day(3, 6)) {
+		t.Fatalf("Expected the skipped runs, got: %#v", page.Runs)
+	}
+
+	// the restart after resuming doesn't catch them up
+	daily.Pause = nil
+	restarted := New(newFakeClock(day(3, 18)), dispatch, 1)
+	restarted.Load([]job.Job{daily})
+	if err := restarted.CatchUp(runs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFire(t, dispatched, nil)
+}
+
+// waitStatus blocks until the stored Run reaches the status
+func waitStatus(t *testing.T, runs store.RunStore, id string, status job.Status) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if run, err := runs.GetRun(id); err == nil && run.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the run %s to be %s", id, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	j := testJob(t, "slow", "0 0 6 * * *")
+	j.Command, j.Args = "sleep", []string{"10"}
+	j.Config.Concurrency = job.Concurrency{Policy: job.ConcurrencyQueue}
+
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{Grace: time.Second}, runs)
+	runner.Done = func(executor.Result, error) {}
+	if _, ok := runner.Cancel("first", "alice"); ok {
+		t.Fatalf("Expected the unknown run")
+	}
+
+	runner.Dispatch(j, job.Run{ID: "first", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	runner.Dispatch(j, job.Run{ID: "second", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	waitStatus(t, runs, "first", job.StatusRunning)
+
+	// the queued run cancelled in the store never starts
+	second, _ := runs.GetRun("second")
+	second.Transition(job.StatusCancelled, time.Now())
+	runs.SaveRun(second)
+
+	// the queued run cancelled by the runner is left out of the queue
+	runner.Dispatch(j, job.Run{ID: "third", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+	done, ok := runner.Cancel("third", "carol")
+	if !ok {
+		t.Fatalf("Expected the queued run")
+	}
+	<-done
+	third, _ := runs.GetRun("third")
+	if third.Status != job.StatusCancelled || third.CancelledBy != "carol" {
+		t.Fatalf("Unexpected run: %#v", third)
+	}
+
+	done, ok = runner.Cancel("first", "alice")
+	if !ok {
+		t.Fatalf("Expected the running run")
+	}
+	<-done
+	first, _ := runs.GetRun("first")
+	if first.Status != job.StatusCancelled || first.CancelledBy != "alice" || first.EndTime.Sub(first.StartTime) > 5*time.Second {
+		t.Fatalf("Unexpected run: %#v", first)
+	}
+
+	runner.Wait()
+	if got, _ := runs.GetRun("second"); !reflect.DeepEqual(second, got) {
+		t.Fatalf("Expected: %#v, got: %#v", second, got)
+	}
+	if got, _ := runs.GetRun("third"); !reflect.DeepEqual(third, got) {
+		t.Fatalf("Expected: %#v, got: %#v", third, got)
+	}
+}
+
+func TestRunnerCancelRetry(t *testing.T) {
+	j := testJob(t, "flaky", "0 0 6 * * *")
+	j.Command = "false"
+	j.Config.Retry = &job.RetryPolicy{MaxAttempts: 3, InitialDelay: job.Duration(time.Hour)}
+
+	runs := store.NewMemory()
+	runner := NewRunner(context.Background(), executor.Executor{}, runs)
+	runner.Done = func(executor.Result, error) {}
+	runner.Dispatch(j, job.Run{ID: "first", JobID: j.ID, Trigger: job.TriggerSchedule, Attempt: 1})
+
+	deadline := time.Now().Add(5 * time.Second)
+	var page store.RunPage
+	for len(page.Runs) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the queued retry")
+		}
+		time.Sleep(time.Millisecond)
+		page, _ = runs.ListRuns(store.RunFilter{Trigger: []job.Trigger{job.TriggerRetry}})
+	}
+
+	done, ok := runner.Cancel(page.Runs[0].ID, "bob")
+	if !ok {
+		t.Fatalf("Expected the waiting retry")
+	}
+	<-done
+	runner.Wait()
+	if got, _ := runs.GetRun(page.Runs[0].ID); got.Status != job.StatusCancelled || got.CancelledBy != "bob" {
+		t.Fatalf("Unexpected retry: %#v", got)
+	}
+}
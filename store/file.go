@@ -0,0 +1,325 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// minCompaction is the number of the outdated lines of the runs log tolerated regardless of the number of the runs
+const minCompaction = 1000
+
+// File keeps the jobs and the runs in the memory. The jobs are written to a single JSON file after every change,
+// the runs are appended to the log <path>.runs (a JSON line per saved or deleted run), so saving a run doesn't rewrite the history.
+// The log is compacted to the latest version of every run once the outdated lines outnumber the runs (and minCompaction).
+// It's meant for a single instance of plango with a modest number of jobs, all the runs are kept in the memory.
+type File struct {
+	mu    sync.RWMutex
+	path  string
+	state state
+	// logged is the number of the lines of the runs log
+	logged int
+}
+
+// OpenFile loads the jobs and the runs from the files, the missing files are created with the first change
+func OpenFile(path string) (*File, error) {
+	f := &File{path: path, state: newState()}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Unable to read the store %s: %v", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &f.state); err != nil {
+			return nil, fmt.Errorf("Unable to decode the store %s: %v", path, err)
+		}
+	}
+	if f.state.Jobs == nil {
+		f.state.Jobs = make(map[string]job.Job)
+	}
+	if f.state.Runs == nil {
+		f.state.Runs = make(map[string]job.Run)
+	}
+	// the jobs stored before the namespaces were introduced
+	for id, j := range f.state.Jobs {
+		j.Namespace = defaultNamespace(j.Namespace)
+		f.state.Jobs[id] = j
+	}
+
+	// the runs stored in the jobs file before the log are moved to the log
+	legacy := len(f.state.Runs) > 0
+	truncated, err := f.readRuns()
+	if err != nil {
+		return nil, err
+	}
+	// the line cut short is dropped, the following ones are appended after it otherwise
+	if legacy || truncated {
+		if err := f.compactRuns(); err != nil {
+			return nil, err
+		}
+	}
+	if legacy {
+		if err := f.save(f.state); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// runLine is a single line of the runs log, the deleted Run leaves only its ID
+type runLine struct {
+	job.Run
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// runsPath is the path of the runs log
+func (f *File) runsPath() string {
+	return f.path + ".runs"
+}
+
+// readRuns replays the runs log, the later lines replace the earlier ones of the same run.
+// The last line cut short by a crash is ignored, it's reported as truncated.
+func (f *File) readRuns() (truncated bool, err error) {
+	data, err := ioutil.ReadFile(f.runsPath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to read the runs %s: %v", f.runsPath(), err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var run runLine
+		if err := json.Unmarshal(line, &run); err != nil {
+			if i == len(lines)-1 {
+				return true, nil
+			}
+			return false, fmt.Errorf("Unable to decode the runs %s: %v", f.runsPath(), err)
+		}
+		if run.Deleted {
+			delete(f.state.Runs, run.ID)
+		} else {
+			f.state.Runs[run.ID] = run.Run
+		}
+		f.logged++
+	}
+	return false, nil
+}
+
+// appendRun writes the Run at the end of the runs log
+func (f *File) appendRun(run runLine) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(f.runsPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to write the runs %s: %v", f.runsPath(), err)
+	}
+	if _, err := log.Write(append(line, '\n')); err != nil {
+		log.Close()
+		return fmt.Errorf("Unable to write the runs %s: %v", f.runsPath(), err)
+	}
+	if err := log.Sync(); err != nil {
+		log.Close()
+		return fmt.Errorf("Unable to write the runs %s: %v", f.runsPath(), err)
+	}
+	if err := log.Close(); err != nil {
+		return fmt.Errorf("Unable to write the runs %s: %v", f.runsPath(), err)
+	}
+	f.logged++
+	return nil
+}
+
+// compactRuns rewrites the runs log with the latest version of every run
+func (f *File) compactRuns() error {
+	var buf bytes.Buffer
+	for _, run := range f.state.Runs {
+		line, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := writeFile(f.runsPath(), buf.Bytes()); err != nil {
+		return fmt.Errorf("Unable to write the runs %s: %v", f.runsPath(), err)
+	}
+	f.logged = len(f.state.Runs)
+	return nil
+}
+
+// save writes the jobs of the state to the file, the runs are kept by the runs log
+func (f *File) save(s state) error {
+	data, err := json.MarshalIndent(struct {
+		Jobs map[string]job.Job `json:"jobs"`
+	}{s.Jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(f.path, data); err != nil {
+		return fmt.Errorf("Unable to write the store %s: %v", f.path, err)
+	}
+	return nil
+}
+
+// writeFile writes the data to a temporary file and renames it, so the file is never left half-written
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// change applies the change of the jobs to a copy of the state, which replaces the current one once it's saved.
+// The runs aren't copied, they are changed by SaveRun only.
+func (f *File) change(apply func(s state) error) error {
+	s := state{Jobs: make(map[string]job.Job, len(f.state.Jobs)), Runs: f.state.Runs}
+	for id, j := range f.state.Jobs {
+		s.Jobs[id] = j
+	}
+	if err := apply(s); err != nil {
+		return err
+	}
+	if err := f.save(s); err != nil {
+		return err
+	}
+	f.state = s
+	return nil
+}
+
+// Create stores the new Job with a generated ID
+func (f *File) Create(j job.Job) (job.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var created job.Job
+	err := f.change(func(s state) error {
+		var err error
+		created, err = s.create(j)
+		return err
+	})
+	return created, err
+}
+
+// Get finds the Job by its ID
+func (f *File) Get(id string) (job.Job, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state.get(id)
+}
+
+// GetByName finds the Job by its name within the namespace
+func (f *File) GetByName(namespace, name string) (job.Job, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state.getByName(namespace, name)
+}
+
+// List returns all the Jobs ordered by their ID
+func (f *File) List() ([]job.Job, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state.list(), nil
+}
+
+// Update replaces the stored Job with the same ID and version
+func (f *File) Update(j job.Job) (job.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var updated job.Job
+	err := f.change(func(s state) error {
+		var err error
+		updated, err = s.update(j)
+		return err
+	})
+	return updated, err
+}
+
+// Delete removes the Job with the given version
+func (f *File) Delete(id string, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.change(func(s state) error {
+		return s.delete(id, version)
+	})
+}
+
+// SaveRun creates or replaces the Run
+func (f *File) SaveRun(run job.Run) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if run.ID == "" {
+		return fmt.Errorf("Unable to save the run with no ID")
+	}
+	if err := f.appendRun(runLine{Run: run}); err != nil {
+		return err
+	}
+	f.state.saveRun(run)
+	return f.compactIfOutdated()
+}
+
+// DeleteRun removes the Run
+func (f *File) DeleteRun(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.state.getRun(id); err != nil {
+		return err
+	}
+	if err := f.appendRun(runLine{Run: job.Run{ID: id}, Deleted: true}); err != nil {
+		return err
+	}
+	f.state.deleteRun(id)
+	return f.compactIfOutdated()
+}
+
+// compactIfOutdated compacts the runs log once the outdated lines outnumber the runs
+func (f *File) compactIfOutdated() error {
+	if f.logged-len(f.state.Runs) > len(f.state.Runs)+minCompaction {
+		return f.compactRuns()
+	}
+	return nil
+}
+
+// GetRun finds the Run by its ID
+func (f *File) GetRun(id string) (job.Run, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state.getRun(id)
+}
+
+// ListRuns returns the page of the Runs selected by the filter
+func (f *File) ListRuns(filter RunFilter) (RunPage, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state.listRuns(filter)
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestNewID(t *testing.T) {
+	seen := make(map[string]bool)
+	last := ""
+	for i := 0; i < 10000; i++ {
+		id := NewID()
+		if len(id) != 26 {
+			t.Fatalf("Expected 26 characters, got: %v", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID: %v", id)
+		}
+		if id <= last {
+			t.Fatalf("Expected %v to be sorted after %v", id, last)
+		}
+		seen[id] = true
+		last = id
+	}
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// Memory keeps the jobs and the runs only in the memory, they are lost with the end of the process
+type Memory struct {
+	mu    sync.RWMutex
+	state state
+}
+
+// NewMemory prepares an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{state: newState()}
+}
+
+// Create stores the new Job with a generated ID
+func (m *Memory) Create(j job.Job) (job.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.create(j)
+}
+
+// Get finds the Job by its ID
+func (m *Memory) Get(id string) (job.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.get(id)
+}
+
+// GetByName finds the Job by its name within the namespace
+func (m *Memory) GetByName(namespace, name string) (job.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.getByName(namespace, name)
+}
+
+// List returns all the Jobs ordered by their ID
+func (m *Memory) List() ([]job.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.list(), nil
+}
+
+// Update replaces the stored Job with the same ID and version
+func (m *Memory) Update(j job.Job) (job.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.update(j)
+}
+
+// Delete removes the Job with the given version
+func (m *Memory) Delete(id string, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.delete(id, version)
+}
+
+// SaveRun creates or replaces the Run
+func (m *Memory) SaveRun(run job.Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.saveRun(run)
+}
+
+// GetRun finds the Run by its ID
+func (m *Memory) GetRun(id string) (job.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.getRun(id)
+}
+
+// DeleteRun removes the Run
+func (m *Memory) DeleteRun(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.deleteRun(id)
+}
+
+// ListRuns returns the page of the Runs selected by the filter
+func (m *Memory) ListRuns(f RunFilter) (RunPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.listRuns(f)
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// ErrInvalidCursor is returned when the cursor of the RunFilter wasn't issued by the store
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// RunStore persists the Runs.
+// SaveRun creates the Run or replaces the stored one with the same ID, the Runs are kept after their Job is deleted.
+type RunStore interface {
+	SaveRun(run job.Run) error
+	GetRun(id string) (job.Run, error)
+	ListRuns(f RunFilter) (RunPage, error)
+	// DeleteRun removes the Run that won't happen, i.e. the planned Run no longer planned
+	DeleteRun(id string) error
+}
+
+// Store persists both the Jobs and their Runs
+type Store interface {
+	JobStore
+	RunStore
+}
+
+// RunFilter selects the Runs listed by ListRuns, the zero values don't filter anything
+type RunFilter struct {
+	JobID   string
+	Status  []job.Status
+	Trigger []job.Trigger
+	// From and To limit the StartTime of the Runs to [From, To)
+	From, To time.Time
+	// Ascending orders the Runs by the StartTime from the oldest, the newest come first by default
+	Ascending bool
+	// Limit is the size of the page, zero lists all the Runs
+	Limit int
+	// Cursor is the RunPage.Next of the previous page, empty for the first page
+	Cursor string
+}
+
+// RunPage is a single page of the listed Runs, Next is empty on the last page
+type RunPage struct {
+	Runs []job.Run `json:"runs"`
+	Next string    `json:"next,omitempty"`
+}
+
+// cursor is the position of the last listed Run in the order of the Runs
+type cursor struct {
+	start int64
+	id    string
+}
+
+// startKey orders the Runs by their StartTime, the Runs that never started come first
+func startKey(run job.Run) int64 {
+	if run.StartTime.IsZero() {
+		return 0
+	}
+	return run.StartTime.UnixNano()
+}
+
+func encodeCursor(run job.Run) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(startKey(run), 10) + "/" + run.ID))
+}
+
+// decodeCursor parses the cursor, nil means the first page
+func decodeCursor(c string) (*cursor, error) {
+	if c == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(data), "/", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor{start: start, id: parts[1]}, nil
+}
+
+// passed checks whether the Run follows the cursor in the given order
+func (c cursor) passed(run job.Run, ascending bool) bool {
+	start := startKey(run)
+	if ascending {
+		return start > c.start || (start == c.start && run.ID > c.id)
+	}
+	return start < c.start || (start == c.start && run.ID < c.id)
+}
+
+// matches checks the Run against all the filters but the cursor
+func (f RunFilter) matches(run job.Run) bool {
+	if f.JobID != "" && run.JobID != f.JobID {
+		return false
+	}
+	if len(f.Status) > 0 && !containsStatus(f.Status, run.Status) {
+		return false
+	}
+	if len(f.Trigger) > 0 && !containsTrigger(f.Trigger, run.Trigger) {
+		return false
+	}
+	if !f.From.IsZero() && startKey(run) < f.From.UnixNano() {
+		return false
+	}
+	if !f.To.IsZero() && startKey(run) >= f.To.UnixNano() {
+		return false
+	}
+	return true
+}
+
+func containsStatus(list []job.Status, s job.Status) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTrigger(list []job.Trigger, t job.Trigger) bool {
+	for _, v := range list {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate cuts the page from the Runs sorted after the cursor, the store lists one extra Run to tell if there are more
+func paginate(runs []job.Run, limit int) RunPage {
+	if limit <= 0 || len(runs) <= limit {
+		return RunPage{Runs: runs}
+	}
+	runs = runs[:limit]
+	return RunPage{Runs: runs, Next: encodeCursor(runs[limit-1])}
+}
+
+func (s state) saveRun(run job.Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("Unable to save the run with no ID")
+	}
+	s.Runs[run.ID] = run
+	return nil
+}
+
+func (s state) getRun(id string) (job.Run, error) {
+	run, ok := s.Runs[id]
+	if !ok {
+		return job.Run{}, ErrNotFound
+	}
+	return run, nil
+}
+
+func (s state) deleteRun(id string) error {
+	if _, ok := s.Runs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.Runs, id)
+	return nil
+}
+
+func (s state) listRuns(f RunFilter) (RunPage, error) {
+	after, err := decodeCursor(f.Cursor)
+	if err != nil {
+		return RunPage{}, err
+	}
+
+	runs := make([]job.Run, 0)
+	for _, run := range s.Runs {
+		if f.matches(run) && (after == nil || after.passed(run, f.Ascending)) {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, k int) bool {
+		a, b := startKey(runs[i]), startKey(runs[k])
+		if a == b {
+			return (runs[i].ID < runs[k].ID) == f.Ascending
+		}
+		return (a < b) == f.Ascending
+	})
+	return paginate(runs, f.Limit), nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// testRuns are the runs of two jobs started every hour, the last one never started
+func testRuns() []job.Run {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []job.Run{
+		{ID: "r1", JobID: "a", Status: job.StatusSucceeded, Trigger: job.TriggerSchedule},
+		{ID: "r2", JobID: "b", Status: job.StatusFailed, Trigger: job.TriggerSchedule},
+		{ID: "r3", JobID: "a", Status: job.StatusFailed, Trigger: job.TriggerManual},
+		{ID: "r4", JobID: "a", Status: job.StatusSucceeded, Trigger: job.TriggerRetry},
+		{ID: "r5", JobID: "b", Status: job.StatusSkipped, Trigger: job.TriggerSchedule},
+	}
+	for ix := range runs[:4] {
+		runs[ix].StartTime = start.Add(time.Duration(ix) * time.Hour)
+		runs[ix].EndTime = runs[ix].StartTime.Add(time.Minute)
+	}
+	return runs
+}
+
+func runIDs(runs []job.Run) []string {
+	ids := make([]string, 0, len(runs))
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	return ids
+}
+
+func TestRunStore(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		filter RunFilter
+		want   []string
+	}{
+		"all":          {filter: RunFilter{}, want: []string{"r4", "r3", "r2", "r1", "r5"}},
+		"ascending":    {filter: RunFilter{Ascending: true}, want: []string{"r5", "r1", "r2", "r3", "r4"}},
+		"job":          {filter: RunFilter{JobID: "a"}, want: []string{"r4", "r3", "r1"}},
+		"status":       {filter: RunFilter{Status: []job.Status{job.StatusFailed, job.StatusSkipped}}, want: []string{"r3", "r2", "r5"}},
+		"trigger":      {filter: RunFilter{Trigger: []job.Trigger{job.TriggerSchedule}, JobID: "b"}, want: []string{"r2", "r5"}},
+		"time range":   {filter: RunFilter{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)}, want: []string{"r3", "r2"}},
+		"open ended":   {filter: RunFilter{From: start.Add(2 * time.Hour)}, want: []string{"r4", "r3"}},
+		"no match":     {filter: RunFilter{JobID: "c"}, want: []string{}},
+		"single page":  {filter: RunFilter{Limit: 10}, want: []string{"r4", "r3", "r2", "r1", "r5"}},
+		"exact page":   {filter: RunFilter{Limit: 2, JobID: "b"}, want: []string{"r2", "r5"}},
+		"page by page": {filter: RunFilter{Limit: 2}, want: []string{"r4", "r3", "r2", "r1", "r5"}},
+		"pages asc":    {filter: RunFilter{Limit: 2, Ascending: true}, want: []string{"r5", "r1", "r2", "r3", "r4"}},
+	}
+
+	for name, st := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, run := range testRuns() {
+				// the runs are saved once started and once more finished
+				started := run
+				started.Status = job.StatusRunning
+				if err := st.SaveRun(started); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if err := st.SaveRun(run); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			want := testRuns()[2]
+			if got, err := st.GetRun("r3"); err != nil || !reflect.DeepEqual(want, got) {
+				t.Fatalf("Expected: %#v, got: %#v %v", want, got, err)
+			}
+			if _, err := st.GetRun("missing"); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+			if err := st.SaveRun(job.Run{}); err == nil {
+				t.Fatalf("Expected an error for the run with no ID")
+			}
+			if err := st.SaveRun(job.Run{ID: "planned", JobID: "a", Status: job.StatusPlanned}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := st.DeleteRun("planned"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := st.GetRun("planned"); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+			if err := st.DeleteRun("planned"); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+			if _, err := st.ListRuns(RunFilter{Cursor: "nonsense"}); err != ErrInvalidCursor {
+				t.Fatalf("Expected: %v, got: %v", ErrInvalidCursor, err)
+			}
+
+			for name, test := range tests {
+				got := make([]job.Run, 0)
+				filter := test.filter
+				for pages := 0; pages < 10; pages++ {
+					page, err := st.ListRuns(filter)
+					if err != nil {
+						t.Fatalf("%s: Unexpected error: %v", name, err)
+					}
+					if filter.Limit > 0 && len(page.Runs) > filter.Limit {
+						t.Fatalf("%s: Expected at most %v runs, got: %v", name, filter.Limit, len(page.Runs))
+					}
+					got = append(got, page.Runs...)
+					if page.Next == "" {
+						break
+					}
+					filter.Cursor = page.Next
+				}
+				if !reflect.DeepEqual(test.want, runIDs(got)) {
+					t.Fatalf("%s: Expected: %#v, got: %#v", name, test.want, runIDs(got))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kubistmi/plango/job"
+)
+
+// migrations define the schema of the SQL store, the n-th migration upgrades the schema to the version n+1.
+// Never change the released migrations, always append a new one.
+var migrations = []string{
+	`CREATE TABLE jobs (
+		id      TEXT PRIMARY KEY,
+		name    TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		data    TEXT NOT NULL
+	)`,
+	`ALTER TABLE jobs ADD COLUMN namespace TEXT NOT NULL DEFAULT 'default'`,
+	`CREATE UNIQUE INDEX jobs_namespace_name ON jobs (namespace, name)`,
+	// trigger is a reserved word, the start_time is in nanoseconds, see startKey
+	`CREATE TABLE runs (
+		id          TEXT PRIMARY KEY,
+		job_id      TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		run_trigger TEXT NOT NULL,
+		start_time  INTEGER NOT NULL,
+		data        TEXT NOT NULL
+	)`,
+	`CREATE INDEX runs_start_time ON runs (start_time, id)`,
+	`CREATE INDEX runs_job_id ON runs (job_id, start_time, id)`,
+}
+
+// SQL keeps the jobs and the runs in a database accessed by database/sql.
+// The queries use `?` placeholders and are tested against SQLite.
+type SQL struct {
+	db *sql.DB
+}
+
+// OpenSQL prepares the store and upgrades the schema of the database to the latest version
+func OpenSQL(db *sql.DB) (*SQL, error) {
+	s := &SQL{db: db}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies the migrations newer than the version recorded in the table schema_migrations
+func (s *SQL) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("Unable to prepare the schema migrations: %v", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("Unable to read the schema version: %v", err)
+	}
+
+	for ix := current; ix < len(migrations); ix++ {
+		err := s.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[ix]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, ix+1)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Unable to migrate the schema to version %v: %v", ix+1, err)
+		}
+	}
+	return nil
+}
+
+// inTx runs the function in a transaction, which is committed unless the function fails
+func (s *SQL) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// jobColumns are the columns decoded by scanJob
+const jobColumns = `id, namespace, version, data`
+
+// scanJob decodes the Job from the row with the jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (job.Job, error) {
+	var j job.Job
+	var id, namespace, data string
+	var version int
+
+	if err := row.Scan(&id, &namespace, &version, &data); err != nil {
+		return job.Job{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return job.Job{}, fmt.Errorf("Unable to decode the job %s: %v", id, err)
+	}
+	j.ID = id
+	j.Namespace = namespace
+	j.Version = version
+	return j, nil
+}
+
+// checkName fails with ErrNameTaken if any other Job in the namespace has the name,
+// the unique index guards the same but its errors differ among the databases
+func checkName(tx *sql.Tx, j job.Job) error {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM jobs WHERE namespace = ? AND name = ? AND id <> ?`, j.Namespace, j.Name, j.ID).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+// Create stores the new Job with a generated ID
+func (s *SQL) Create(j job.Job) (job.Job, error) {
+	j.ID = NewID()
+	j.Namespace = defaultNamespace(j.Namespace)
+	j.Version = 1
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return job.Job{}, err
+	}
+
+	err = s.inTx(func(tx *sql.Tx) error {
+		if err := checkName(tx, j); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO jobs (id, namespace, name, version, data) VALUES (?, ?, ?, ?, ?)`,
+			j.ID, j.Namespace, j.Name, j.Version, string(data))
+		return err
+	})
+	if err == ErrNameTaken {
+		return job.Job{}, err
+	}
+	if err != nil {
+		return job.Job{}, fmt.Errorf("Unable to create the job: %v", err)
+	}
+	return j, nil
+}
+
+// Get finds the Job by its ID
+func (s *SQL) Get(id string) (job.Job, error) {
+	j, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return job.Job{}, ErrNotFound
+	}
+	return j, err
+}
+
+// GetByName finds the Job by its name within the namespace
+func (s *SQL) GetByName(namespace, name string) (job.Job, error) {
+	j, err := scanJob(s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE namespace = ? AND name = ?`, namespace, name))
+	if err == sql.ErrNoRows {
+		return job.Job{}, ErrNotFound
+	}
+	return j, err
+}
+
+// List returns all the Jobs ordered by their ID
+func (s *SQL) List() ([]job.Job, error) {
+	rows, err := s.db.Query(`SELECT ` + jobColumns + ` FROM jobs`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]job.Job, 0)
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Update replaces the stored Job with the same ID and version
+func (s *SQL) Update(j job.Job) (job.Job, error) {
+	expected := j.Version
+	j.Namespace = defaultNamespace(j.Namespace)
+	j.Version++
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return job.Job{}, err
+	}
+
+	err = s.inTx(func(tx *sql.Tx) error {
+		if err := checkName(tx, j); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE jobs SET namespace = ?, name = ?, version = ?, data = ? WHERE id = ? AND version = ?`,
+			j.Namespace, j.Name, j.Version, string(data), j.ID, expected)
+		if err != nil {
+			return fmt.Errorf("Unable to update the job %s: %v", j.ID, err)
+		}
+		return s.checkAffected(tx, res, j.ID)
+	})
+	if err != nil {
+		return job.Job{}, err
+	}
+	return j, nil
+}
+
+// Delete removes the Job with the given version
+func (s *SQL) Delete(id string, version int) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM jobs WHERE id = ? AND version = ?`, id, version)
+		if err != nil {
+			return fmt.Errorf("Unable to delete the job %s: %v", id, err)
+		}
+		return s.checkAffected(tx, res, id)
+	})
+}
+
+// checkAffected tells apart the missing Job and the version conflict when nothing was changed
+func (s *SQL) checkAffected(tx *sql.Tx, res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM jobs WHERE id = ?`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+// SaveRun creates or replaces the Run
+func (s *SQL) SaveRun(run job.Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("Unable to save the run with no ID")
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	err = s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE runs SET job_id = ?, status = ?, run_trigger = ?, start_time = ?, data = ? WHERE id = ?`,
+			run.JobID, string(run.Status), string(run.Trigger), startKey(run), string(data), run.ID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO runs (id, job_id, status, run_trigger, start_time, data) VALUES (?, ?, ?, ?, ?, ?)`,
+			run.ID, run.JobID, string(run.Status), string(run.Trigger), startKey(run), string(data))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to save the run %s: %v", run.ID, err)
+	}
+	return nil
+}
+
+// DeleteRun removes the Run
+func (s *SQL) DeleteRun(id string) error {
+	res, err := s.db.Exec(`DELETE FROM runs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("Unable to delete the run %s: %v", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanRun decodes the Run from the row with its data
+func scanRun(row interface{ Scan(...interface{}) error }) (job.Run, error) {
+	var run job.Run
+	var id, data string
+
+	if err := row.Scan(&id, &data); err != nil {
+		return job.Run{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &run); err != nil {
+		return job.Run{}, fmt.Errorf("Unable to decode the run %s: %v", id, err)
+	}
+	return run, nil
+}
+
+// GetRun finds the Run by its ID
+func (s *SQL) GetRun(id string) (job.Run, error) {
+	run, err := scanRun(s.db.QueryRow(`SELECT id, data FROM runs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return job.Run{}, ErrNotFound
+	}
+	return run, err
+}
+
+// placeholders lists n `?` separated by commas
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// ListRuns returns the page of the Runs selected by the filter
+func (s *SQL) ListRuns(f RunFilter) (RunPage, error) {
+	after, err := decodeCursor(f.Cursor)
+	if err != nil {
+		return RunPage{}, err
+	}
+
+	where := []string{"1 = 1"}
+	args := make([]interface{}, 0)
+	if f.JobID != "" {
+		where = append(where, "job_id = ?")
+		args = append(args, f.JobID)
+	}
+	if len(f.Status) > 0 {
+		where = append(where, "status IN ("+placeholders(len(f.Status))+")")
+		for _, st := range f.Status {
+			args = append(args, string(st))
+		}
+	}
+	if len(f.Trigger) > 0 {
+		where = append(where, "run_trigger IN ("+placeholders(len(f.Trigger))+")")
+		for _, tr := range f.Trigger {
+			args = append(args, string(tr))
+		}
+	}
+	if !f.From.IsZero() {
+		where = append(where, "start_time >= ?")
+		args = append(args, f.From.UnixNano())
+	}
+	if !f.To.IsZero() {
+		where = append(where, "start_time < ?")
+		args = append(args, f.To.UnixNano())
+	}
+
+	order, cmp := "DESC", "<"
+	if f.Ascending {
+		order, cmp = "ASC", ">"
+	}
+	if after != nil {
+		where = append(where, "(start_time "+cmp+" ? OR (start_time = ? AND id "+cmp+" ?))")
+		args = append(args, after.start, after.start, after.id)
+	}
+
+	query := `SELECT id, data FROM runs WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY start_time ` + order + `, id ` + order
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit+1)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return RunPage{}, fmt.Errorf("Unable to list the runs: %v", err)
+	}
+	defer rows.Close()
+
+	runs := make([]job.Run, 0)
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return RunPage{}, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return RunPage{}, err
+	}
+	return paginate(runs, f.Limit), nil
+}
@@ -0,0 +1,139 @@
+// Package store persists the jobs and their runs.
+package store
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/kubistmi/plango/job"
+)
+
+var (
+	// ErrNotFound is returned when the requested Job or Run doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the Job was changed since it was read, i.e. its version differs
+	ErrConflict = errors.New("version conflict")
+	// ErrNameTaken is returned when another Job in the namespace has the same name
+	ErrNameTaken = errors.New("name already taken")
+)
+
+// JobStore persists the Jobs.
+// Create generates the ID of the Job (see NewID), the names must be unique within the namespace.
+// The versions implement the optimistic locking: Create sets the version to 1, Update and Delete
+// fail with ErrConflict unless the given version matches the stored one and Update increases it.
+type JobStore interface {
+	Create(j job.Job) (job.Job, error)
+	Get(id string) (job.Job, error)
+	GetByName(namespace, name string) (job.Job, error)
+	List() ([]job.Job, error)
+	Update(j job.Job) (job.Job, error)
+	Delete(id string, version int) error
+}
+
+// state is the in-memory content of the Memory and File stores
+type state struct {
+	Jobs map[string]job.Job `json:"jobs"`
+	Runs map[string]job.Run `json:"runs"`
+}
+
+func newState() state {
+	return state{Jobs: make(map[string]job.Job), Runs: make(map[string]job.Run)}
+}
+
+// Lookup finds the Job by its ID or, if there's no such ID, by its name within the namespace
+func Lookup(s JobStore, namespace, ref string) (job.Job, error) {
+	j, err := s.Get(ref)
+	if err != ErrNotFound {
+		return j, err
+	}
+	return s.GetByName(defaultNamespace(namespace), ref)
+}
+
+func defaultNamespace(namespace string) string {
+	if namespace == "" {
+		return job.DefaultNamespace
+	}
+	return namespace
+}
+
+// nameTaken checks whether any other Job in the namespace has the name
+func (s state) nameTaken(j job.Job) bool {
+	other, err := s.getByName(j.Namespace, j.Name)
+	return err == nil && other.ID != j.ID
+}
+
+func (s state) create(j job.Job) (job.Job, error) {
+	j.ID = NewID()
+	j.Namespace = defaultNamespace(j.Namespace)
+	j.Version = 1
+	if s.nameTaken(j) {
+		return job.Job{}, ErrNameTaken
+	}
+	s.Jobs[j.ID] = j
+	return j, nil
+}
+
+func (s state) getByName(namespace, name string) (job.Job, error) {
+	for _, j := range s.Jobs {
+		if defaultNamespace(j.Namespace) == namespace && j.Name == name {
+			return j, nil
+		}
+	}
+	return job.Job{}, ErrNotFound
+}
+
+func (s state) get(id string) (job.Job, error) {
+	j, ok := s.Jobs[id]
+	if !ok {
+		return job.Job{}, ErrNotFound
+	}
+	return j, nil
+}
+
+func (s state) list() []job.Job {
+	jobs := make([]job.Job, 0, len(s.Jobs))
+	for _, j := range s.Jobs {
+		jobs = append(jobs, j)
+	}
+	sortJobs(jobs)
+	return jobs
+}
+
+func (s state) update(j job.Job) (job.Job, error) {
+	old, ok := s.Jobs[j.ID]
+	if !ok {
+		return job.Job{}, ErrNotFound
+	}
+	if old.Version != j.Version {
+		return job.Job{}, ErrConflict
+	}
+	j.Namespace = defaultNamespace(j.Namespace)
+	if s.nameTaken(j) {
+		return job.Job{}, ErrNameTaken
+	}
+	j.Version++
+	s.Jobs[j.ID] = j
+	return j, nil
+}
+
+func (s state) delete(id string, version int) error {
+	old, ok := s.Jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if old.Version != version {
+		return ErrConflict
+	}
+	delete(s.Jobs, id)
+	return nil
+}
+
+// sortJobs orders the jobs by their ID, i.e. by the time of creation (the shorter IDs predate the ULIDs)
+func sortJobs(jobs []job.Job) {
+	sort.Slice(jobs, func(i, k int) bool {
+		if len(jobs[i].ID) != len(jobs[k].ID) {
+			return len(jobs[i].ID) < len(jobs[k].ID)
+		}
+		return jobs[i].ID < jobs[k].ID
+	})
+}
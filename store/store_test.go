@@ -0,0 +1,256 @@
+package store
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kubistmi/plango/job"
+	"github.com/kubistmi/plango/schedule"
+)
+
+// backends prepares a fresh instance of every Store implementation
+func backends(t *testing.T) map[string]Store {
+	t.Helper()
+	dir := t.TempDir()
+
+	file, err := OpenFile(filepath.Join(dir, "jobs.json"))
+	if err != nil {
+		t.Fatalf("Unable to open the file store: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "jobs.db"))
+	if err != nil {
+		t.Fatalf("Unable to open the database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	sqlite, err := OpenSQL(db)
+	if err != nil {
+		t.Fatalf("Unable to open the SQL store: %v", err)
+	}
+
+	return map[string]Store{"memory": NewMemory(), "file": file, "sqlite": sqlite}
+}
+
+func testJob(name string) job.Job {
+	daily, _ := schedule.ParseSchedule("0 0 2 * * *")
+	return job.Job{Name: name, Schedule: daily, Active: true, Command: "backup.sh", Args: []string{"--full"}, Config: job.Config{Env: map[string]string{"A": "B"}}}
+}
+
+func TestJobStore(t *testing.T) {
+	for name, st := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			first, err := st.Create(testJob("backup"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			second, _ := st.Create(testJob("report"))
+			if first.ID == "" || first.ID == second.ID || first.Version != 1 {
+				t.Fatalf("Unexpected IDs and versions: %#v %#v", first, second)
+			}
+
+			got, err := st.Get(first.ID)
+			if err != nil || !reflect.DeepEqual(first, got) {
+				t.Fatalf("Expected: %#v, got: %#v %v", first, got, err)
+			}
+
+			first.Command = "backup2.sh"
+			updated, err := st.Update(first)
+			if err != nil || updated.Version != 2 || updated.Command != "backup2.sh" {
+				t.Fatalf("Unexpected update: %#v %v", updated, err)
+			}
+
+			// the stale version is rejected
+			if _, err := st.Update(first); err != ErrConflict {
+				t.Fatalf("Expected: %v, got: %v", ErrConflict, err)
+			}
+			if err := st.Delete(first.ID, first.Version); err != ErrConflict {
+				t.Fatalf("Expected: %v, got: %v", ErrConflict, err)
+			}
+
+			list, err := st.List()
+			if err != nil || !reflect.DeepEqual([]job.Job{updated, second}, list) {
+				t.Fatalf("Expected: %#v, got: %#v %v", []job.Job{updated, second}, list, err)
+			}
+
+			if err := st.Delete(updated.ID, updated.Version); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := st.Get(updated.ID); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+			if _, err := st.Update(updated); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+			if err := st.Delete(updated.ID, updated.Version); err != ErrNotFound {
+				t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+			}
+		})
+	}
+}
+
+func TestJobNames(t *testing.T) {
+	for name, st := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			backup, _ := st.Create(testJob("backup"))
+			if backup.Namespace != job.DefaultNamespace {
+				t.Fatalf("Expected: %v, got: %v", job.DefaultNamespace, backup.Namespace)
+			}
+			if _, err := st.Create(testJob("backup")); err != ErrNameTaken {
+				t.Fatalf("Expected: %v, got: %v", ErrNameTaken, err)
+			}
+
+			// the same name is allowed in another namespace
+			other := testJob("backup")
+			other.Namespace = "team"
+			other, err := st.Create(other)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// renaming to a taken name is rejected
+			report, _ := st.Create(testJob("report"))
+			report.Name = "backup"
+			if _, err := st.Update(report); err != ErrNameTaken {
+				t.Fatalf("Expected: %v, got: %v", ErrNameTaken, err)
+			}
+
+			tests := map[string]struct {
+				namespace string
+				ref       string
+				want      job.Job
+				err       error
+			}{
+				"by id":             {ref: other.ID, want: other},
+				"by name":           {ref: "backup", want: backup},
+				"by name namespace": {namespace: "team", ref: "backup", want: other},
+				"missing name":      {namespace: "team", ref: "report", err: ErrNotFound},
+			}
+			for name, test := range tests {
+				got, err := Lookup(st, test.namespace, test.ref)
+				if err != test.err || !reflect.DeepEqual(test.want, got) {
+					t.Fatalf("%s: expected: %#v %v, got: %#v %v", name, test.want, test.err, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	st, _ := OpenFile(path)
+	created, _ := st.Create(testJob("backup"))
+
+	reopened, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, err := reopened.Get(created.ID)
+	if err != nil || !reflect.DeepEqual(created, got) {
+		t.Fatalf("Expected: %#v, got: %#v %v", created, got, err)
+	}
+	if next, _ := reopened.Create(testJob("report")); next.ID == created.ID {
+		t.Fatalf("Expected a new ID, got: %v", next.ID)
+	}
+}
+
+func TestFileRuns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.json")
+	first := job.Run{ID: "first", JobID: "job", Status: job.StatusSucceeded}
+	second := job.Run{ID: "second", JobID: "job", Status: job.StatusFailed}
+
+	// the runs of the jobs file predating the log are moved to the log
+	ioutil.WriteFile(path, []byte(`{"jobs": {}, "runs": {"first": {"id": "first", "jobId": "job", "status": "succeeded"}}}`), 0644)
+	st, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, err := st.GetRun("first"); err != nil || !reflect.DeepEqual(first, got) {
+		t.Fatalf("Expected: %#v, got: %#v %v", first, got, err)
+	}
+	if data, _ := ioutil.ReadFile(path); strings.Contains(string(data), "first") {
+		t.Fatalf("Expected the runs out of the jobs file, got: %s", data)
+	}
+
+	// the saved runs are appended to the log, the jobs file isn't rewritten
+	before, _ := os.Stat(path)
+	st.SaveRun(second)
+	if after, _ := os.Stat(path); !after.ModTime().Equal(before.ModTime()) {
+		t.Fatalf("Expected the jobs file intact")
+	}
+
+	// the line cut short by a crash is dropped
+	log, _ := os.OpenFile(path+".runs", os.O_WRONLY|os.O_APPEND, 0644)
+	log.WriteString(`{"id": "third", "jobId": "jo`)
+	log.Close()
+	reopened, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reopened.SaveRun(job.Run{ID: "third", JobID: "job", Status: job.StatusRunning})
+	reopened, err = OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	page, _ := reopened.ListRuns(RunFilter{})
+	if len(page.Runs) != 3 {
+		t.Fatalf("Expected 3 runs, got: %#v", page.Runs)
+	}
+
+	// the deleted run stays deleted once the log is replayed
+	reopened.DeleteRun("third")
+	reopened, _ = OpenFile(path)
+	if _, err := reopened.GetRun("third"); err != ErrNotFound {
+		t.Fatalf("Expected: %v, got: %v", ErrNotFound, err)
+	}
+
+	// the outdated lines are compacted
+	for i := 0; i < minCompaction+10; i++ {
+		second.ExitCode = i
+		reopened.SaveRun(second)
+	}
+	if reopened.logged > len(reopened.state.Runs)+minCompaction+3 {
+		t.Fatalf("Expected the log compacted, got %v lines", reopened.logged)
+	}
+	reopened, _ = OpenFile(path)
+	if got, _ := reopened.GetRun("second"); got.ExitCode != minCompaction+9 {
+		t.Fatalf("Expected: %v, got: %#v", minCompaction+9, got)
+	}
+}
+
+func TestSQLMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("Unable to open the database: %v", err)
+	}
+	defer db.Close()
+
+	st, err := OpenSQL(db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	created, _ := st.Create(testJob("backup"))
+
+	// opening the migrated database again keeps the data
+	again, err := OpenSQL(db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := again.Get(created.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var version int
+	db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if version != len(migrations) {
+		t.Fatalf("Expected: %v, got: %v", len(migrations), version)
+	}
+}